@@ -0,0 +1,213 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+
+	"github.com/chun37/doppelcord/internal/config"
+	"github.com/chun37/doppelcord/internal/database"
+	"github.com/chun37/doppelcord/internal/handler"
+	"github.com/chun37/doppelcord/internal/lifecycle"
+	"github.com/chun37/doppelcord/internal/llm"
+	"github.com/chun37/doppelcord/internal/metrics"
+	"github.com/chun37/doppelcord/internal/prompt"
+	"github.com/chun37/doppelcord/internal/repository/cached"
+	"github.com/chun37/doppelcord/internal/repository/postgres"
+	"github.com/chun37/doppelcord/internal/server"
+	"github.com/chun37/doppelcord/internal/shard"
+	"github.com/chun37/doppelcord/internal/usage"
+)
+
+// runBot はDiscordボットを起動し、シグナルを受信するまで実行する
+func runBot() {
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(cfg)
+
+	// シグナル受信でキャンセルされるルートコンテキスト
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM, os.Interrupt)
+	defer stop()
+
+	pool, err := database.NewPostgresPool(ctx, newDatabaseConfig(cfg))
+	if err != nil {
+		log.Fatal("Failed to connect to database:", err)
+	}
+
+	fmt.Println("Connected to database")
+
+	pgUserRepo := postgres.NewUserRepository(pool)
+	userRepo := cached.NewCachedUserRepository(pgUserRepo)
+
+	if err := userRepo.LoadAll(ctx); err != nil {
+		log.Fatal("Failed to load users into cache:", err)
+	}
+	fmt.Println("Loaded users into cache")
+
+	// 常駐ジョブ（ルートコンテキストのキャンセルで停止する）
+	var jobs sync.WaitGroup
+
+	// 他のインスタンスでのユーザー登録をキャッシュに反映
+	userListener := postgres.NewUserChangeListener(pool)
+	jobs.Go(func() {
+		userRepo.Sync(ctx, userListener, cfg.UserCache.ReloadInterval)
+	})
+
+	msgRepo := postgres.NewMessageRepository(pool)
+	templateRepo := postgres.NewPromptTemplateRepository(pool)
+	generationRepo := postgres.NewGenerationRepository(pool)
+	usageService := usage.NewService(generationRepo, newUsageLimits(cfg))
+
+	llmClient := llm.NewClient(newLLMConfig(cfg))
+	fmt.Println("LLM client initialized")
+
+	tracker := lifecycle.NewTracker(ctx)
+	interactionHandler := handler.NewInteractionHandler(handler.InteractionDeps{
+		UserRepo:       userRepo,
+		MessageRepo:    msgRepo,
+		TemplateRepo:   templateRepo,
+		GenerationRepo: generationRepo,
+		StatsRepo:      postgres.NewStatsRepository(pool),
+		ConsentRepo:    postgres.NewConsentRepository(pool),
+		GameScoreRepo:  postgres.NewGameScoreRepository(pool),
+		ScheduleRepo:   postgres.NewScheduleRepository(pool),
+		AwayRepo:       postgres.NewAwayRepository(pool),
+		LLMClient:      llmClient,
+		Usage:          usageService,
+		Tracker:        tracker,
+		Prompt: handler.PromptConfig{
+			MaxMessages:    cfg.Prompt.MaxMessages,
+			MaxPromptChars: cfg.Prompt.MaxChars,
+			Location:       cfg.Location(),
+			Strategy:       prompt.Strategy(cfg.Prompt.Strategy),
+		},
+		DM: handler.DMConfig{
+			MaxTurns:   cfg.DM.MaxTurns,
+			SessionTTL: cfg.DM.SessionTTL,
+		},
+	})
+	msgHandler := handler.NewMessageHandler(handler.MessageDeps{
+		UserRepo:  userRepo,
+		MsgRepo:   msgRepo,
+		Tracker:   tracker,
+		Responder: interactionHandler,
+		StoreDMs:  cfg.DM.StoreHistory,
+	})
+
+	shards, err := shard.NewManager(shard.Config{
+		Token:   cfg.Discord.Token,
+		Intents: discordgo.IntentsGuildMessages | discordgo.IntentsDirectMessages | discordgo.IntentsMessageContent,
+		Count:   cfg.Sharding.Count,
+		IDs:     cfg.Sharding.IDs,
+	})
+	if err != nil {
+		log.Fatal("Error creating Discord sessions:", err)
+	}
+	fmt.Printf("Running %d of %d shards\n", len(shards.Sessions()), shards.Count())
+
+	removeHandlers := []func(){
+		shards.AddHandler(msgHandler.Handle),
+		shards.AddHandler(interactionHandler.Handle),
+	}
+
+	// ヘルスチェック・メトリクス用HTTPサーバー（HTTP_ADDRが設定されている場合のみ）
+	var healthServer *server.HealthServer
+	if addr := cfg.HTTP.Addr; addr != "" {
+		metrics.Default.NewGaugeFunc(
+			"doppelcord_user_cache_size",
+			"Number of registered users held in the in-memory cache.",
+			func() float64 { return float64(userRepo.Len()) },
+		)
+
+		checks := map[string]server.Check{
+			"database": pool.Ping,
+			"llm":      llmClient.Ping,
+		}
+		for _, dg := range shards.Sessions() {
+			shardID := dg.ShardID
+			checks[fmt.Sprintf("discord_shard_%d", shardID)] = func(ctx context.Context) error {
+				for _, st := range shards.Statuses() {
+					if st.ID == shardID && !st.Connected {
+						return errors.New("gateway not connected")
+					}
+				}
+				return nil
+			}
+		}
+
+		healthServer = server.NewHealthServer(addr, metrics.Default, checks)
+		if err := healthServer.Start(); err != nil {
+			log.Fatal("Error starting health server:", err)
+		}
+		fmt.Printf("Health server listening on %s\n", addr)
+	}
+
+	err = shards.Open()
+	if err != nil {
+		log.Fatal("Error opening connection:", err)
+	}
+
+	// ギルド単位の処理は担当シャードを持つプロセスのみで実行する
+	if dg := shards.SessionForGuild(cfg.Discord.GuildID); dg != nil {
+		for _, cmd := range commands {
+			_, err := dg.ApplicationCommandCreate(dg.State.User.ID, cfg.Discord.GuildID, cmd)
+			if err != nil {
+				log.Printf("Cannot create '%s' command: %v", cmd.Name, err)
+			} else {
+				fmt.Printf("Command '%s' registered\n", cmd.Name)
+			}
+		}
+	} else {
+		fmt.Printf("Guild %s is owned by another shard; skipping command registration\n", cfg.Discord.GuildID)
+	}
+
+	// 定期投稿は担当シャードのギルドのみ実行し、レプリカ間の重複はアドバイザリロックで防ぐ
+	jobs.Go(func() {
+		interactionHandler.RunSchedules(ctx, shards)
+	})
+
+	fmt.Println("Bot is now running. Press CTRL-C to exit.")
+
+	<-ctx.Done()
+	stop()
+
+	fmt.Println("\nShutting down gracefully...")
+
+	// 1. 新しいイベントを受け付けないようにハンドラーを解除
+	for _, remove := range removeHandlers {
+		remove()
+	}
+
+	// 2. 実行中の処理（LLM呼び出しやメッセージ保存）の完了を待つ
+	if err := tracker.Drain(cfg.ShutdownTimeout); err != nil {
+		log.Printf("In-flight work did not finish within %s: %v", cfg.ShutdownTimeout, err)
+	} else {
+		fmt.Println("All in-flight work finished")
+	}
+
+	// 3. 常駐ジョブの停止を待ち、接続を閉じる
+	jobs.Wait()
+	if healthServer != nil {
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		if err := healthServer.Shutdown(shutdownCtx); err != nil {
+			log.Printf("Error shutting down health server: %v", err)
+		}
+		cancel()
+	}
+	if err := shards.Close(); err != nil {
+		log.Printf("Error closing Discord sessions: %v", err)
+	}
+	pool.Close()
+
+	fmt.Println("Shutdown complete")
+}
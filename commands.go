@@ -0,0 +1,395 @@
+package main
+
+import "github.com/bwmarrin/discordgo"
+
+var manageGuildPermission int64 = discordgo.PermissionManageGuild
+
+var commands = []*discordgo.ApplicationCommand{
+	{
+		// メッセージのコンテキストメニュー（右クリック > アプリ）から使う
+		Name: "Reply as me",
+		NameLocalizations: &map[discordgo.Locale]string{
+			discordgo.Japanese: "自分として返信",
+		},
+		Type: discordgo.MessageApplicationCommand,
+	},
+	{
+		Name:        "register",
+		Description: "ユーザーを登録します",
+	},
+	{
+		Name:        "test",
+		Description: "LLMにテストメッセージを送信します",
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionNumber,
+				Name:        "temperature",
+				Description: "生成のランダムさ（0〜2、省略時は設定値）",
+				MinValue:    floatPtr(0),
+				MaxValue:    2,
+			},
+			lengthOption(),
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "topic",
+				Description: "メッセージの話題",
+				MaxLength:   200,
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "reply_to",
+				Description: "返信するメッセージのリンクまたはID",
+			},
+			dateOption("as_of", "この日時点の自分としてなりきる（YYYY-MM-DD）"),
+			dateOption("from", "この日以降の履歴だけでなりきる（YYYY-MM-DD）"),
+			dateOption("to", "この日までの履歴だけでなりきる（YYYY-MM-DD）"),
+		},
+	},
+	{
+		Name:        "search",
+		Description: "自分の過去のメッセージを検索します",
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "query",
+				Description: "検索するキーワード（空白区切りですべてを含むメッセージ）",
+				Required:    true,
+				MaxLength:   100,
+			},
+			{
+				Type:         discordgo.ApplicationCommandOptionChannel,
+				Name:         "channel",
+				Description:  "検索するチャンネル",
+				ChannelTypes: []discordgo.ChannelType{discordgo.ChannelTypeGuildText},
+			},
+			dateOption("from", "この日以降のメッセージ（YYYY-MM-DD）"),
+			dateOption("to", "この日までのメッセージ（YYYY-MM-DD）"),
+		},
+	},
+	{
+		Name:        "stats",
+		Description: "自分の発言の統計を表示します",
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionBoolean,
+				Name:        "chart",
+				Description: "時間帯別のグラフ画像を付ける",
+			},
+		},
+	},
+	{
+		Name:        "consent",
+		Description: "他のユーザーが /duel などであなたのなりきりを使うことを許可・拒否します",
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionBoolean,
+				Name:        "allow",
+				Description: "許可する場合はTrue（省略時は現在の設定を表示）",
+			},
+		},
+	},
+	{
+		Name:        "duel",
+		Description: "登録ユーザーのなりきり同士で会話させます",
+		Options: []*discordgo.ApplicationCommandOption{
+			{Type: discordgo.ApplicationCommandOptionUser, Name: "a", Description: "1人目", Required: true},
+			{Type: discordgo.ApplicationCommandOptionUser, Name: "b", Description: "2人目", Required: true},
+			{
+				Type:        discordgo.ApplicationCommandOptionInteger,
+				Name:        "turns",
+				Description: "合計のターン数（2〜12、省略時は6）",
+				MinValue:    floatPtr(2),
+				MaxValue:    12,
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "topic",
+				Description: "会話の話題",
+				MaxLength:   200,
+			},
+			{Type: discordgo.ApplicationCommandOptionUser, Name: "c", Description: "3人目"},
+		},
+	},
+	{
+		Name:        "blend",
+		Description: "複数のユーザーの文体を混ぜ合わせたなりきりでメッセージを生成します",
+		Options: []*discordgo.ApplicationCommandOption{
+			{Type: discordgo.ApplicationCommandOptionUser, Name: "a", Description: "1人目", Required: true},
+			{Type: discordgo.ApplicationCommandOptionUser, Name: "b", Description: "2人目", Required: true},
+			{Type: discordgo.ApplicationCommandOptionUser, Name: "c", Description: "3人目"},
+			{Type: discordgo.ApplicationCommandOptionUser, Name: "d", Description: "4人目"},
+			{Type: discordgo.ApplicationCommandOptionUser, Name: "e", Description: "5人目"},
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "weights",
+				Description: "各ユーザーの比率（a,b,c…の順に1〜10、例: 2,1,1。省略時は均等）",
+				MaxLength:   30,
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "topic",
+				Description: "メッセージの話題",
+				MaxLength:   200,
+			},
+		},
+	},
+	{
+		Name:        "channel-voice",
+		Description: "このチャンネル全体の口調を真似てメッセージを生成します",
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "topic",
+				Description: "メッセージの話題",
+				MaxLength:   200,
+			},
+		},
+	},
+	{
+		Name:        "game",
+		Description: "「Who said it?」ゲームで遊びます",
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "start",
+				Description: "ゲームを開始します",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "mode",
+						Description: "出題形式（省略時は本物かなりきりか）",
+						Choices: []*discordgo.ApplicationCommandOptionChoice{
+							{Name: "本物？なりきり？", Value: "real_or_fake"},
+							{Name: "誰の発言？", Value: "who"},
+						},
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionInteger,
+						Name:        "rounds",
+						Description: "ラウンド数（1〜10、省略時は3）",
+						MinValue:    floatPtr(1),
+						MaxValue:    10,
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionInteger,
+						Name:        "seconds",
+						Description: "1ラウンドの制限時間（10〜120秒、省略時は30秒）",
+						MinValue:    floatPtr(10),
+						MaxValue:    120,
+					},
+				},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "leaderboard",
+				Description: "サーバーのランキングを表示します",
+			},
+		},
+	},
+	{
+		Name:        "schedule",
+		Description: "自分のなりきりの定期投稿を設定します",
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "cron",
+				Description: "決まった時刻に投稿します（例: 毎朝8時は 0 8 * * *）",
+				Options: []*discordgo.ApplicationCommandOption{
+					scheduleChannelOption(),
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "spec",
+						Description: "実行時刻（分 時 日 月 曜日 のcron形式）",
+						Required:    true,
+						MaxLength:   100,
+					},
+					scheduleTopicOption(),
+					dateOption("until", "この日まで投稿する（YYYY-MM-DD）"),
+				},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "random",
+				Description: "ランダムな間隔で投稿します",
+				Options: []*discordgo.ApplicationCommandOption{
+					scheduleChannelOption(),
+					{
+						Type:        discordgo.ApplicationCommandOptionInteger,
+						Name:        "min_minutes",
+						Description: "最短の間隔（分、30以上）",
+						Required:    true,
+						MinValue:    floatPtr(30),
+						MaxValue:    7 * 24 * 60,
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionInteger,
+						Name:        "max_minutes",
+						Description: "最長の間隔（分、省略時は最短と同じ）",
+						MinValue:    floatPtr(30),
+						MaxValue:    7 * 24 * 60,
+					},
+					scheduleTopicOption(),
+					dateOption("until", "この日まで投稿する（YYYY-MM-DD）"),
+				},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "list",
+				Description: "自分のスケジュールを表示します",
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "remove",
+				Description: "スケジュールを削除します",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionInteger,
+						Name:        "id",
+						Description: "削除するスケジュールの番号（/schedule list で確認）",
+						Required:    true,
+						MinValue:    floatPtr(1),
+					},
+				},
+			},
+		},
+	},
+	{
+		Name:        "away",
+		Description: "不在中にメンションされたとき、なりきりが代わりに返信します",
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "on",
+				Description: "不在に設定します",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "until",
+						Description: "この日まで不在（YYYY-MM-DD）",
+						Required:    true,
+						MinLength:   intPtr(10),
+						MaxLength:   10,
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "note",
+						Description: "不在の理由（例: 旅行中）",
+						MaxLength:   200,
+					},
+				},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "off",
+				Description: "不在設定を解除します",
+			},
+		},
+	},
+	{
+		Name:        "usage",
+		Description: "LLMの使用量と利用上限を表示します",
+	},
+	{
+		Name:                     "template",
+		Description:              "プロンプトテンプレートを管理します（管理者向け）",
+		DefaultMemberPermissions: &manageGuildPermission,
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "preview",
+				Description: "適用されるテンプレートを展開して表示します",
+				Options: []*discordgo.ApplicationCommandOption{
+					templateUserOption("プレビューするユーザー（省略時はサーバーのテンプレート）"),
+				},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "set",
+				Description: "テンプレートを新しいバージョンとして設定します",
+				Options: []*discordgo.ApplicationCommandOption{
+					templateUserOption("設定するユーザー（省略時はサーバー全体）"),
+				},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "rollback",
+				Description: "テンプレートを以前のバージョンに戻します",
+				Options: []*discordgo.ApplicationCommandOption{
+					templateUserOption("対象のユーザー（省略時はサーバー全体）"),
+					{
+						Type:        discordgo.ApplicationCommandOptionInteger,
+						Name:        "version",
+						Description: "戻すバージョン（省略時は1つ前）",
+						MinValue:    floatPtr(1),
+					},
+				},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        "history",
+				Description: "テンプレートのバージョン履歴を表示します",
+				Options: []*discordgo.ApplicationCommandOption{
+					templateUserOption("対象のユーザー（省略時はサーバー全体）"),
+				},
+			},
+		},
+	},
+}
+
+func templateUserOption(description string) *discordgo.ApplicationCommandOption {
+	return &discordgo.ApplicationCommandOption{
+		Type:        discordgo.ApplicationCommandOptionUser,
+		Name:        "user",
+		Description: description,
+	}
+}
+
+func lengthOption() *discordgo.ApplicationCommandOption {
+	return &discordgo.ApplicationCommandOption{
+		Type:        discordgo.ApplicationCommandOptionString,
+		Name:        "length",
+		Description: "生成するメッセージの長さ",
+		Choices: []*discordgo.ApplicationCommandOptionChoice{
+			{Name: "短め", Value: "short"},
+			{Name: "普通", Value: "normal"},
+			{Name: "長め", Value: "long"},
+		},
+	}
+}
+
+func dateOption(name, description string) *discordgo.ApplicationCommandOption {
+	return &discordgo.ApplicationCommandOption{
+		Type:        discordgo.ApplicationCommandOptionString,
+		Name:        name,
+		Description: description,
+		MinLength:   intPtr(10),
+		MaxLength:   10,
+	}
+}
+
+func scheduleChannelOption() *discordgo.ApplicationCommandOption {
+	return &discordgo.ApplicationCommandOption{
+		Type:         discordgo.ApplicationCommandOptionChannel,
+		Name:         "channel",
+		Description:  "投稿するチャンネル",
+		ChannelTypes: []discordgo.ChannelType{discordgo.ChannelTypeGuildText},
+		Required:     true,
+	}
+}
+
+func scheduleTopicOption() *discordgo.ApplicationCommandOption {
+	return &discordgo.ApplicationCommandOption{
+		Type:        discordgo.ApplicationCommandOptionString,
+		Name:        "topic",
+		Description: "投稿の話題（例: 朝のあいさつ）",
+		MaxLength:   200,
+	}
+}
+
+func intPtr(v int) *int {
+	return &v
+}
+
+func floatPtr(v float64) *float64 {
+	return &v
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/chun37/doppelcord/internal/config"
+	"github.com/chun37/doppelcord/internal/database"
+	"github.com/chun37/doppelcord/internal/domain"
+	"github.com/chun37/doppelcord/internal/eval"
+	"github.com/chun37/doppelcord/internal/llm"
+	"github.com/chun37/doppelcord/internal/prompt"
+	"github.com/chun37/doppelcord/internal/repository/postgres"
+)
+
+// runEval はユーザーの直近の発言を取り分け、現在のプロンプトで生成した候補と文体を比較する
+func runEval(args []string) {
+	fs := flag.NewFlagSet("eval", flag.ExitOnError)
+	userID := fs.String("user", "", "評価するユーザーのDiscord ID（-input未指定時は必須）")
+	input := fs.String("input", "", "DBの代わりに使うメッセージのJSONファイル")
+	label := fs.String("label", "current", "レポートに記録するプロンプトのバージョン名")
+	systemTemplate := fs.String("system-template", "", "評価するsystemプロンプトのテンプレートファイル（省略時はデフォルト）")
+	userTemplate := fs.String("user-template", "", "評価するuserプロンプトのテンプレートファイル（省略時はデフォルト）")
+	holdout := fs.Int("holdout", 20, "評価用に取り分ける直近のメッセージ数")
+	candidates := fs.Int("candidates", 20, "生成する候補数")
+	strategy := fs.String("strategy", "", "発言履歴をLLMに渡す形式（template・chat、省略時はPROMPT_STRATEGY）")
+	judge := fs.Bool("judge", false, "LLMによる評価も行う")
+	llmURL := fs.String("llm-url", "", "LLM_API_URLを上書き（モックサーバーの指定など）")
+	out := fs.String("out", "", "レポートの出力先（省略時は標準出力）")
+	baseline := fs.String("baseline", "", "比較するベースラインのレポート")
+	maxDrop := fs.Float64("max-drop", 0.05, "ベースラインから許容するスコア低下幅")
+	fs.Parse(args)
+
+	if *userID == "" && *input == "" {
+		fmt.Fprintln(os.Stderr, "either -user or -input is required")
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	tmpl := prompt.DefaultTemplate
+	if *systemTemplate != "" {
+		data, err := os.ReadFile(*systemTemplate)
+		if err != nil {
+			log.Fatal("Failed to read system template:", err)
+		}
+		tmpl.System = string(data)
+	}
+	if *userTemplate != "" {
+		data, err := os.ReadFile(*userTemplate)
+		if err != nil {
+			log.Fatal("Failed to read user template:", err)
+		}
+		tmpl.User = string(data)
+	}
+	if err := tmpl.Validate(); err != nil {
+		log.Fatal("Invalid template:", err)
+	}
+
+	if *llmURL != "" {
+		os.Setenv("LLM_API_URL", *llmURL)
+	}
+	cfg, err := config.LoadSections(config.SectionLLM)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if *strategy == "" {
+		*strategy = cfg.Prompt.Strategy
+	}
+	if s := prompt.Strategy(*strategy); s != prompt.StrategyTemplate && s != prompt.StrategyChat {
+		log.Fatalf("-strategy must be template or chat, got %q", *strategy)
+	}
+	if cfg.LLM.Seed != nil && *candidates > 1 {
+		log.Printf("LLM_SEED is set: every candidate is generated from the same prompt and will likely be identical")
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM, os.Interrupt)
+	defer stop()
+
+	var messages []*domain.Message
+	if *input != "" {
+		messages, err = eval.LoadMessages(*input)
+		if err != nil {
+			log.Fatal("Failed to load messages:", err)
+		}
+	} else {
+		messages, err = loadEvalMessages(ctx, cfg, *userID, *holdout+cfg.Prompt.MaxMessages)
+		if err != nil {
+			log.Fatal("Failed to load messages:", err)
+		}
+	}
+
+	llmClient := llm.NewClient(newLLMConfig(cfg))
+
+	runner := eval.NewRunner(llmClient, eval.Options{
+		Label:          *label,
+		Model:          cfg.LLM.Model,
+		Holdout:        *holdout,
+		Candidates:     *candidates,
+		MaxHistory:     cfg.Prompt.MaxMessages,
+		MaxPromptChars: cfg.Prompt.MaxChars,
+		Strategy:       prompt.Strategy(*strategy),
+		Judge:          *judge,
+		Template:       tmpl,
+	})
+	report, err := runner.Run(ctx, *userID, messages)
+	if err != nil {
+		log.Fatal("Evaluation failed:", err)
+	}
+
+	w := os.Stdout
+	if *out != "" {
+		f, err := os.Create(*out)
+		if err != nil {
+			log.Fatal("Failed to create report file:", err)
+		}
+		defer f.Close()
+		w = f
+	}
+	if err := report.Write(w); err != nil {
+		log.Fatal("Failed to write report:", err)
+	}
+	fmt.Fprintf(os.Stderr, "Score: %.4f (%s)\n", report.Score, report.Label)
+
+	if *baseline != "" {
+		base, err := eval.ReadReport(*baseline)
+		if err != nil {
+			log.Fatal("Failed to read baseline:", err)
+		}
+		if err := report.CheckRegression(base, *maxDrop); err != nil {
+			log.Fatal(err)
+		}
+		fmt.Fprintf(os.Stderr, "Baseline: %.4f (%s)\n", base.Score, base.Label)
+	}
+}
+
+func loadEvalMessages(ctx context.Context, cfg *config.Config, userID string, limit int) ([]*domain.Message, error) {
+	if err := cfg.ValidateSections(config.SectionDatabase); err != nil {
+		return nil, err
+	}
+
+	pool, err := database.NewPostgresPool(ctx, newDatabaseConfig(cfg))
+	if err != nil {
+		return nil, err
+	}
+	defer pool.Close()
+
+	return postgres.NewMessageRepository(pool).FindByDiscordID(ctx, userID, limit, nil)
+}
+
+// runMockLLM はCIでの評価用にOpenAI互換のモックAPIサーバーを起動する
+func runMockLLM(args []string) {
+	fs := flag.NewFlagSet("mock-llm", flag.ExitOnError)
+	addr := fs.String("addr", "127.0.0.1:8089", "リッスンするアドレス")
+	fs.Parse(args)
+
+	fmt.Printf("Mock LLM server listening on %s\n", *addr)
+	log.Fatal(http.ListenAndServe(*addr, eval.NewMockLLMHandler()))
+}
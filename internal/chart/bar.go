@@ -0,0 +1,79 @@
+package chart
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+)
+
+// BarChart は棒グラフの描画設定。文字は描画しないため、軸の説明は埋め込み先で補う
+type BarChart struct {
+	Width, Height int
+	// TickEvery は何本ごとに目盛りを付けるか（0の場合は付けない）
+	TickEvery int
+	Bar       color.Color
+	// Highlight は最大値の棒の色
+	Highlight  color.Color
+	Background color.Color
+	Axis       color.Color
+}
+
+// DefaultBarChart はDiscordの埋め込みに合わせた既定の設定
+var DefaultBarChart = BarChart{
+	Width:      480,
+	Height:     160,
+	TickEvery:  6,
+	Bar:        color.RGBA{0x58, 0x65, 0xf2, 0xff},
+	Highlight:  color.RGBA{0xeb, 0x45, 0x9e, 0xff},
+	Background: color.RGBA{0x2b, 0x2d, 0x31, 0xff},
+	Axis:       color.RGBA{0x94, 0x9b, 0xa4, 0xff},
+}
+
+const padding = 8
+
+// PNG は値ごとの棒グラフをPNGで返す
+func (c BarChart) PNG(values []int64) ([]byte, error) {
+	img := image.NewRGBA(image.Rect(0, 0, c.Width, c.Height))
+	draw.Draw(img, img.Bounds(), image.NewUniform(c.Background), image.Point{}, draw.Src)
+
+	baseline := c.Height - padding
+	fill(img, image.Rect(padding, baseline, c.Width-padding, baseline+1), c.Axis)
+
+	if len(values) > 0 {
+		var maxValue int64
+		for _, v := range values {
+			maxValue = max(maxValue, v)
+		}
+
+		slot := (c.Width - 2*padding) / len(values)
+		gap := max(slot/5, 1)
+		plotHeight := baseline - padding
+		for i, v := range values {
+			x := padding + i*slot
+			if c.TickEvery > 0 && i%c.TickEvery == 0 {
+				fill(img, image.Rect(x, baseline, x+1, baseline+padding/2), c.Axis)
+			}
+			if maxValue == 0 || v == 0 {
+				continue
+			}
+			h := max(int(int64(plotHeight)*v/maxValue), 1)
+			barColor := c.Bar
+			if v == maxValue {
+				barColor = c.Highlight
+			}
+			fill(img, image.Rect(x+gap/2, baseline-h, x+slot-gap/2, baseline), barColor)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func fill(img draw.Image, r image.Rectangle, c color.Color) {
+	draw.Draw(img, r, image.NewUniform(c), image.Point{}, draw.Src)
+}
@@ -0,0 +1,191 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"time"
+
+	"github.com/joho/godotenv"
+	"gopkg.in/yaml.v3"
+)
+
+// Config はアプリケーション全体の設定
+type Config struct {
+	Discord         DiscordConfig   `yaml:"discord"`
+	Sharding        ShardingConfig  `yaml:"sharding"`
+	Database        DatabaseConfig  `yaml:"database"`
+	LLM             LLMConfig       `yaml:"llm"`
+	Prompt          PromptConfig    `yaml:"prompt"`
+	Quota           QuotaConfig     `yaml:"quota"`
+	DM              DMConfig        `yaml:"dm"`
+	UserCache       UserCacheConfig `yaml:"user_cache"`
+	HTTP            HTTPConfig      `yaml:"http"`
+	ShutdownTimeout time.Duration   `yaml:"shutdown_timeout"`
+}
+
+// DiscordConfig はDiscord接続の設定
+type DiscordConfig struct {
+	Token   string `yaml:"token"`
+	GuildID string `yaml:"guild_id"`
+}
+
+// ShardingConfig はGatewayのシャーディング設定
+type ShardingConfig struct {
+	// Count は全体のシャード数。0の場合はDiscordの推奨値を使う
+	Count int `yaml:"count"`
+	// IDs はこのプロセスが担当するシャードID。空の場合は全シャードを担当する
+	IDs []int `yaml:"ids"`
+}
+
+// DatabaseConfig はPostgreSQL接続とコネクションプールの設定
+type DatabaseConfig struct {
+	Host            string        `yaml:"host"`
+	Port            string        `yaml:"port"`
+	User            string        `yaml:"user"`
+	Password        string        `yaml:"password"`
+	Name            string        `yaml:"name"`
+	SSLMode         string        `yaml:"sslmode"`
+	MaxConns        int32         `yaml:"max_conns"`
+	MinConns        int32         `yaml:"min_conns"`
+	MaxConnLifetime time.Duration `yaml:"max_conn_lifetime"`
+	MaxConnIdleTime time.Duration `yaml:"max_conn_idle_time"`
+}
+
+// LLMConfig はLLM APIの設定
+type LLMConfig struct {
+	APIURL  string        `yaml:"api_url"`
+	APIKey  string        `yaml:"api_key"`
+	Model   string        `yaml:"model"`
+	Timeout time.Duration `yaml:"timeout"`
+
+	// 生成パラメータのデフォルト値（未設定の場合はAPIのデフォルト）
+	Temperature    *float64 `yaml:"temperature"`
+	TopP           *float64 `yaml:"top_p"`
+	MaxTokens      *int     `yaml:"max_tokens"`
+	Stop           []string `yaml:"stop"`
+	Seed           *int64   `yaml:"seed"`
+	ResponseFormat string   `yaml:"response_format"`
+}
+
+// PromptConfig はプロンプト生成の設定
+type PromptConfig struct {
+	MaxMessages int `yaml:"max_messages"`
+	MaxChars    int `yaml:"max_chars"`
+	// Timezone はテンプレートの時間帯の判定に使うIANAタイムゾーン名
+	Timezone string `yaml:"timezone"`
+	// Strategy は発言履歴をLLMに渡す形式（template: systemプロンプトにまとめる、chat: 会話として並べる）
+	Strategy string `yaml:"strategy"`
+}
+
+// QuotaConfig はユーザーごとのLLM利用制限の設定。0の項目は無制限
+type QuotaConfig struct {
+	DailyTokens   int64         `yaml:"daily_tokens"`
+	MonthlyTokens int64         `yaml:"monthly_tokens"`
+	Cooldown      time.Duration `yaml:"cooldown"`
+}
+
+// DMConfig はDMでのなりきりとの会話の設定
+type DMConfig struct {
+	// StoreHistory がtrueの場合はDMのメッセージも発言履歴として保存する
+	StoreHistory bool `yaml:"store_history"`
+	// MaxTurns は会話の文脈として保持するやり取りの数
+	MaxTurns int `yaml:"max_turns"`
+	// SessionTTL は最後のやり取りから会話の文脈を保持する時間
+	SessionTTL time.Duration `yaml:"session_ttl"`
+}
+
+// UserCacheConfig は登録ユーザーキャッシュの設定
+type UserCacheConfig struct {
+	ReloadInterval time.Duration `yaml:"reload_interval"`
+}
+
+// HTTPConfig はヘルスチェック・メトリクス用HTTPサーバーの設定
+type HTTPConfig struct {
+	Addr string `yaml:"addr"`
+}
+
+// Default はデフォルト値を設定したConfigを返す
+func Default() *Config {
+	return &Config{
+		Sharding: ShardingConfig{
+			Count: 1,
+		},
+		Database: DatabaseConfig{
+			Host:            "localhost",
+			Port:            "5432",
+			SSLMode:         "disable",
+			MaxConns:        10,
+			MinConns:        2,
+			MaxConnLifetime: time.Hour,
+			MaxConnIdleTime: 30 * time.Minute,
+		},
+		LLM: LLMConfig{
+			Timeout: 30 * time.Second,
+		},
+		Prompt: PromptConfig{
+			MaxMessages: 100,
+			MaxChars:    10000,
+			Timezone:    "Asia/Tokyo",
+			Strategy:    "template",
+		},
+		DM: DMConfig{
+			MaxTurns:   10,
+			SessionTTL: time.Hour,
+		},
+		UserCache: UserCacheConfig{
+			ReloadInterval: 5 * time.Minute,
+		},
+		ShutdownTimeout: 30 * time.Second,
+	}
+}
+
+// Load は設定を読み込み、すべてのセクションを検証する。優先順位は 環境変数 > 設定ファイル > デフォルト値。
+// .envファイルは存在すれば環境変数として読み込み、設定ファイルはCONFIG_FILEで指定された場合のみ読み込む
+func Load() (*Config, error) {
+	return LoadSections(AllSections...)
+}
+
+// LoadSections は設定を読み込み、指定したセクションのみを検証する。
+// evalサブコマンドなどDiscordに接続しない用途で使う
+func LoadSections(sections ...Section) (*Config, error) {
+	if err := godotenv.Load(); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("failed to load .env file: %w", err)
+	}
+
+	cfg := Default()
+
+	if path := os.Getenv("CONFIG_FILE"); path != "" {
+		if err := cfg.loadFile(path); err != nil {
+			return nil, err
+		}
+	}
+
+	// 環境変数の解析エラーと検証エラーをまとめて報告する
+	if err := errors.Join(cfg.applyEnv(), cfg.ValidateSections(sections...)); err != nil {
+		return nil, fmt.Errorf("invalid configuration:\n%w", err)
+	}
+	return cfg, nil
+}
+
+// Location はPROMPT_TIMEZONEのタイムゾーンを返す。検証済みの設定では失敗しない
+func (c *Config) Location() *time.Location {
+	loc, err := time.LoadLocation(c.Prompt.Timezone)
+	if err != nil {
+		return time.Local
+	}
+	return loc
+}
+
+func (c *Config) loadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	if err := yaml.Unmarshal(data, c); err != nil {
+		return fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+	return nil
+}
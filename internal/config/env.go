@@ -0,0 +1,215 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// applyEnv は環境変数で設定を上書きする。値の解析に失敗したものはまとめてエラーにする
+func (c *Config) applyEnv() error {
+	var errs []error
+
+	envString("DISCORD_BOT_TOKEN", &c.Discord.Token)
+	envString("GUILD_ID", &c.Discord.GuildID)
+	errs = append(errs,
+		envInt("SHARD_COUNT", &c.Sharding.Count),
+		envIntList("SHARD_IDS", &c.Sharding.IDs),
+	)
+
+	envString("DB_HOST", &c.Database.Host)
+	envString("DB_PORT", &c.Database.Port)
+	envString("DB_USER", &c.Database.User)
+	envString("DB_PASSWORD", &c.Database.Password)
+	envString("DB_NAME", &c.Database.Name)
+	envString("DB_SSLMODE", &c.Database.SSLMode)
+	errs = append(errs,
+		envInt32("DB_MAX_CONNS", &c.Database.MaxConns),
+		envInt32("DB_MIN_CONNS", &c.Database.MinConns),
+		envDuration("DB_MAX_CONN_LIFETIME", &c.Database.MaxConnLifetime),
+		envDuration("DB_MAX_CONN_IDLE_TIME", &c.Database.MaxConnIdleTime),
+	)
+
+	envString("LLM_API_URL", &c.LLM.APIURL)
+	envString("LLM_API_KEY", &c.LLM.APIKey)
+	envString("LLM_MODEL", &c.LLM.Model)
+	errs = append(errs,
+		envDuration("LLM_TIMEOUT", &c.LLM.Timeout),
+		envFloatPtr("LLM_TEMPERATURE", &c.LLM.Temperature),
+		envFloatPtr("LLM_TOP_P", &c.LLM.TopP),
+		envIntPtr("LLM_MAX_TOKENS", &c.LLM.MaxTokens),
+		envInt64Ptr("LLM_SEED", &c.LLM.Seed),
+	)
+	envStringList("LLM_STOP", &c.LLM.Stop)
+	envString("LLM_RESPONSE_FORMAT", &c.LLM.ResponseFormat)
+
+	errs = append(errs,
+		envInt("PROMPT_MAX_MESSAGES", &c.Prompt.MaxMessages),
+		envInt("PROMPT_MAX_CHARS", &c.Prompt.MaxChars),
+	)
+	envString("PROMPT_TIMEZONE", &c.Prompt.Timezone)
+	envString("PROMPT_STRATEGY", &c.Prompt.Strategy)
+
+	errs = append(errs,
+		envInt64("QUOTA_DAILY_TOKENS", &c.Quota.DailyTokens),
+		envInt64("QUOTA_MONTHLY_TOKENS", &c.Quota.MonthlyTokens),
+		envDuration("QUOTA_COOLDOWN", &c.Quota.Cooldown),
+	)
+
+	errs = append(errs,
+		envBool("DM_STORE_HISTORY", &c.DM.StoreHistory),
+		envInt("DM_MAX_TURNS", &c.DM.MaxTurns),
+		envDuration("DM_SESSION_TTL", &c.DM.SessionTTL),
+	)
+
+	errs = append(errs, envDuration("USER_CACHE_RELOAD_INTERVAL", &c.UserCache.ReloadInterval))
+
+	envString("HTTP_ADDR", &c.HTTP.Addr)
+	errs = append(errs, envDuration("SHUTDOWN_TIMEOUT", &c.ShutdownTimeout))
+
+	return errors.Join(errs...)
+}
+
+func envString(key string, dst *string) {
+	if v, ok := os.LookupEnv(key); ok && v != "" {
+		*dst = v
+	}
+}
+
+func envStringList(key string, dst *[]string) {
+	v, ok := os.LookupEnv(key)
+	if !ok || v == "" {
+		return
+	}
+	var list []string
+	for _, part := range strings.Split(v, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			list = append(list, part)
+		}
+	}
+	*dst = list
+}
+
+func envInt(key string, dst *int) error {
+	v, ok := os.LookupEnv(key)
+	if !ok || v == "" {
+		return nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		return fmt.Errorf("%s: invalid integer %q", key, v)
+	}
+	*dst = n
+	return nil
+}
+
+func envInt32(key string, dst *int32) error {
+	v, ok := os.LookupEnv(key)
+	if !ok || v == "" {
+		return nil
+	}
+	n, err := strconv.ParseInt(v, 10, 32)
+	if err != nil {
+		return fmt.Errorf("%s: invalid integer %q", key, v)
+	}
+	*dst = int32(n)
+	return nil
+}
+
+func envInt64(key string, dst *int64) error {
+	v, ok := os.LookupEnv(key)
+	if !ok || v == "" {
+		return nil
+	}
+	n, err := strconv.ParseInt(v, 10, 64)
+	if err != nil {
+		return fmt.Errorf("%s: invalid integer %q", key, v)
+	}
+	*dst = n
+	return nil
+}
+
+func envIntList(key string, dst *[]int) error {
+	v, ok := os.LookupEnv(key)
+	if !ok || v == "" {
+		return nil
+	}
+	var list []int
+	for _, part := range strings.Split(v, ",") {
+		n, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil {
+			return fmt.Errorf("%s: invalid integer list %q", key, v)
+		}
+		list = append(list, n)
+	}
+	*dst = list
+	return nil
+}
+
+func envIntPtr(key string, dst **int) error {
+	v, ok := os.LookupEnv(key)
+	if !ok || v == "" {
+		return nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		return fmt.Errorf("%s: invalid integer %q", key, v)
+	}
+	*dst = &n
+	return nil
+}
+
+func envInt64Ptr(key string, dst **int64) error {
+	v, ok := os.LookupEnv(key)
+	if !ok || v == "" {
+		return nil
+	}
+	n, err := strconv.ParseInt(v, 10, 64)
+	if err != nil {
+		return fmt.Errorf("%s: invalid integer %q", key, v)
+	}
+	*dst = &n
+	return nil
+}
+
+func envFloatPtr(key string, dst **float64) error {
+	v, ok := os.LookupEnv(key)
+	if !ok || v == "" {
+		return nil
+	}
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		return fmt.Errorf("%s: invalid number %q", key, v)
+	}
+	*dst = &f
+	return nil
+}
+
+func envBool(key string, dst *bool) error {
+	v, ok := os.LookupEnv(key)
+	if !ok || v == "" {
+		return nil
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		return fmt.Errorf("%s: invalid boolean %q", key, v)
+	}
+	*dst = b
+	return nil
+}
+
+func envDuration(key string, dst *time.Duration) error {
+	v, ok := os.LookupEnv(key)
+	if !ok || v == "" {
+		return nil
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		return fmt.Errorf("%s: invalid duration %q", key, v)
+	}
+	*dst = d
+	return nil
+}
@@ -0,0 +1,72 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+const redacted = "[REDACTED]"
+
+// String は秘密情報を伏せた設定内容を返す
+func (c *Config) String() string {
+	var sb strings.Builder
+	line := func(key string, value any) {
+		fmt.Fprintf(&sb, "  %s: %v\n", key, value)
+	}
+
+	sb.WriteString("Configuration:\n")
+	line("discord.token", redact(c.Discord.Token))
+	line("discord.guild_id", c.Discord.GuildID)
+	line("sharding.count", c.Sharding.Count)
+	line("sharding.ids", c.Sharding.IDs)
+	line("database.host", c.Database.Host)
+	line("database.port", c.Database.Port)
+	line("database.user", c.Database.User)
+	line("database.password", redact(c.Database.Password))
+	line("database.name", c.Database.Name)
+	line("database.sslmode", c.Database.SSLMode)
+	line("database.max_conns", c.Database.MaxConns)
+	line("database.min_conns", c.Database.MinConns)
+	line("database.max_conn_lifetime", c.Database.MaxConnLifetime)
+	line("database.max_conn_idle_time", c.Database.MaxConnIdleTime)
+	line("llm.api_url", c.LLM.APIURL)
+	line("llm.api_key", redact(c.LLM.APIKey))
+	line("llm.model", c.LLM.Model)
+	line("llm.timeout", c.LLM.Timeout)
+	line("llm.temperature", optional(c.LLM.Temperature))
+	line("llm.top_p", optional(c.LLM.TopP))
+	line("llm.max_tokens", optional(c.LLM.MaxTokens))
+	line("llm.stop", c.LLM.Stop)
+	line("llm.seed", optional(c.LLM.Seed))
+	line("llm.response_format", c.LLM.ResponseFormat)
+	line("prompt.max_messages", c.Prompt.MaxMessages)
+	line("prompt.max_chars", c.Prompt.MaxChars)
+	line("prompt.timezone", c.Prompt.Timezone)
+	line("prompt.strategy", c.Prompt.Strategy)
+	line("quota.daily_tokens", c.Quota.DailyTokens)
+	line("quota.monthly_tokens", c.Quota.MonthlyTokens)
+	line("quota.cooldown", c.Quota.Cooldown)
+	line("dm.store_history", c.DM.StoreHistory)
+	line("dm.max_turns", c.DM.MaxTurns)
+	line("dm.session_ttl", c.DM.SessionTTL)
+	line("user_cache.reload_interval", c.UserCache.ReloadInterval)
+	line("http.addr", c.HTTP.Addr)
+	line("shutdown_timeout", c.ShutdownTimeout)
+
+	return strings.TrimSuffix(sb.String(), "\n")
+}
+
+func redact(v string) string {
+	if v == "" {
+		return ""
+	}
+	return redacted
+}
+
+// optional は未設定のポインタ値を空文字列として表示する
+func optional[T any](v *T) any {
+	if v == nil {
+		return ""
+	}
+	return *v
+}
@@ -0,0 +1,143 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+)
+
+// Section は検証対象の設定セクション
+type Section int
+
+const (
+	SectionDiscord Section = iota
+	SectionDatabase
+	SectionLLM
+)
+
+// AllSections はボットの実行に必要なすべてのセクション
+var AllSections = []Section{SectionDiscord, SectionDatabase, SectionLLM}
+
+type validator struct {
+	errs []error
+}
+
+func (v *validator) required(name, value string) {
+	if value == "" {
+		v.errs = append(v.errs, fmt.Errorf("%s is required", name))
+	}
+}
+
+func (v *validator) positive(name string, value int64) {
+	if value <= 0 {
+		v.errs = append(v.errs, fmt.Errorf("%s must be positive, got %d", name, value))
+	}
+}
+
+func (v *validator) nonNegative(name string, value int64) {
+	if value < 0 {
+		v.errs = append(v.errs, fmt.Errorf("%s must not be negative, got %d", name, value))
+	}
+}
+
+func (v *validator) fail(format string, args ...any) {
+	v.errs = append(v.errs, fmt.Errorf(format, args...))
+}
+
+// Validate はすべての設定値を検証し、問題をすべてまとめたエラーを返す
+func (c *Config) Validate() error {
+	return c.ValidateSections(AllSections...)
+}
+
+// ValidateSections は指定したセクションと共通のチューニング値を検証し、問題をすべてまとめたエラーを返す
+func (c *Config) ValidateSections(sections ...Section) error {
+	v := &validator{}
+	for _, section := range sections {
+		switch section {
+		case SectionDiscord:
+			c.validateDiscord(v)
+		case SectionDatabase:
+			c.validateDatabase(v)
+		case SectionLLM:
+			c.validateLLM(v)
+		}
+	}
+
+	v.positive("PROMPT_MAX_MESSAGES", int64(c.Prompt.MaxMessages))
+	v.positive("PROMPT_MAX_CHARS", int64(c.Prompt.MaxChars))
+	if _, err := time.LoadLocation(c.Prompt.Timezone); err != nil {
+		v.fail("PROMPT_TIMEZONE is not a valid time zone: %q", c.Prompt.Timezone)
+	}
+	switch c.Prompt.Strategy {
+	case "template", "chat":
+	default:
+		v.fail("PROMPT_STRATEGY must be template or chat, got %q", c.Prompt.Strategy)
+	}
+
+	v.nonNegative("QUOTA_DAILY_TOKENS", c.Quota.DailyTokens)
+	v.nonNegative("QUOTA_MONTHLY_TOKENS", c.Quota.MonthlyTokens)
+	v.nonNegative("QUOTA_COOLDOWN", int64(c.Quota.Cooldown))
+
+	v.positive("DM_MAX_TURNS", int64(c.DM.MaxTurns))
+	v.positive("DM_SESSION_TTL", int64(c.DM.SessionTTL))
+
+	v.positive("USER_CACHE_RELOAD_INTERVAL", int64(c.UserCache.ReloadInterval))
+
+	v.positive("SHUTDOWN_TIMEOUT", int64(c.ShutdownTimeout))
+
+	return errors.Join(v.errs...)
+}
+
+func (c *Config) validateDiscord(v *validator) {
+	v.required("DISCORD_BOT_TOKEN", c.Discord.Token)
+	v.required("GUILD_ID", c.Discord.GuildID)
+
+	if c.Sharding.Count < 0 {
+		v.fail("SHARD_COUNT must not be negative, got %d", c.Sharding.Count)
+	}
+	for _, id := range c.Sharding.IDs {
+		if id < 0 || (c.Sharding.Count > 0 && id >= c.Sharding.Count) {
+			v.fail("SHARD_IDS contains %d, which is out of range for SHARD_COUNT %d", id, c.Sharding.Count)
+		}
+	}
+}
+
+func (c *Config) validateDatabase(v *validator) {
+	v.required("DB_HOST", c.Database.Host)
+	v.required("DB_PORT", c.Database.Port)
+	v.required("DB_USER", c.Database.User)
+	v.required("DB_NAME", c.Database.Name)
+	v.positive("DB_MAX_CONNS", int64(c.Database.MaxConns))
+	if c.Database.MinConns < 0 || c.Database.MinConns > c.Database.MaxConns {
+		v.fail("DB_MIN_CONNS must be between 0 and DB_MAX_CONNS (%d), got %d", c.Database.MaxConns, c.Database.MinConns)
+	}
+	v.positive("DB_MAX_CONN_LIFETIME", int64(c.Database.MaxConnLifetime))
+	v.positive("DB_MAX_CONN_IDLE_TIME", int64(c.Database.MaxConnIdleTime))
+}
+
+func (c *Config) validateLLM(v *validator) {
+	v.required("LLM_API_URL", c.LLM.APIURL)
+	if c.LLM.APIURL != "" {
+		if u, err := url.Parse(c.LLM.APIURL); err != nil || u.Scheme == "" || u.Host == "" {
+			v.fail("LLM_API_URL must be an absolute URL, got %q", c.LLM.APIURL)
+		}
+	}
+	v.required("LLM_MODEL", c.LLM.Model)
+	v.positive("LLM_TIMEOUT", int64(c.LLM.Timeout))
+
+	if t := c.LLM.Temperature; t != nil && (*t < 0 || *t > 2) {
+		v.fail("LLM_TEMPERATURE must be between 0 and 2, got %g", *t)
+	}
+	if p := c.LLM.TopP; p != nil && (*p < 0 || *p > 1) {
+		v.fail("LLM_TOP_P must be between 0 and 1, got %g", *p)
+	}
+	if c.LLM.MaxTokens != nil {
+		v.positive("LLM_MAX_TOKENS", int64(*c.LLM.MaxTokens))
+	}
+	switch c.LLM.ResponseFormat {
+	case "", "text", "json_object":
+	default:
+		v.fail("LLM_RESPONSE_FORMAT must be text or json_object, got %q", c.LLM.ResponseFormat)
+	}
+}
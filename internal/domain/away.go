@@ -0,0 +1,11 @@
+package domain
+
+import "time"
+
+// AwayStatus はユーザーの不在設定。不在中に言及されるとなりきりが代わりに返信する
+type AwayStatus struct {
+	DiscordID string
+	Until     time.Time
+	// Note は不在の理由など（例: 旅行中）。空の場合もある
+	Note string
+}
@@ -0,0 +1,9 @@
+package domain
+
+// GameScore は「Who said it?」ゲームのギルドごとの成績
+type GameScore struct {
+	GuildID      string
+	DiscordID    string
+	Points       int
+	RoundsPlayed int
+}
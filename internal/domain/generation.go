@@ -0,0 +1,61 @@
+package domain
+
+import "time"
+
+// GenerationStatus はLLM生成の結果
+type GenerationStatus string
+
+const (
+	GenerationStatusSuccess GenerationStatus = "success"
+	GenerationStatusError   GenerationStatus = "error"
+)
+
+// Generation はLLMによる1回の生成の記録
+type Generation struct {
+	ID               int64
+	DiscordID        string
+	GuildID          string
+	ChannelID        string
+	Command          string
+	Model            string
+	Status           GenerationStatus
+	Latency          time.Duration
+	PromptTokens     int
+	CompletionTokens int
+	// PromptHash は送信したプロンプトのSHA-256（同じプロンプトによる生成の比較に使う）
+	PromptHash string
+	Content    string
+	CreatedAt  time.Time
+}
+
+// TotalTokens はプロンプトと生成の合計トークン数を返す
+func (g *Generation) TotalTokens() int {
+	return g.PromptTokens + g.CompletionTokens
+}
+
+// FeedbackRating は生成に対する評価
+type FeedbackRating int16
+
+const (
+	FeedbackRatingGood FeedbackRating = 1
+	FeedbackRatingBad  FeedbackRating = -1
+)
+
+// GenerationFeedback はユーザーによる生成の評価。1ユーザーにつき1生成1件
+type GenerationFeedback struct {
+	GenerationID int64
+	DiscordID    string
+	Rating       FeedbackRating
+}
+
+// UsageSummary は期間内のトークン使用量の集計
+type UsageSummary struct {
+	Generations      int
+	PromptTokens     int64
+	CompletionTokens int64
+}
+
+// TotalTokens はプロンプトと生成の合計トークン数を返す
+func (u UsageSummary) TotalTokens() int64 {
+	return u.PromptTokens + u.CompletionTokens
+}
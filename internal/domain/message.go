@@ -8,6 +8,14 @@ type Message struct {
 	ChannelID string
 	MessageID string
 	Content   string
-	CreatedAt time.Time
-	StoredAt  time.Time
+	// ReplyToMessageID は返信先のメッセージID（返信でない場合は空）
+	ReplyToMessageID string
+	// ReplyToAuthorID は返信先のメッセージの送信者のID（取得できない場合は空）
+	ReplyToAuthorID string
+	// ReplyToContent は返信先のメッセージ本文（取得できない場合は空）
+	ReplyToContent string
+	// ThreadParentID はスレッド内の発言の場合、スレッドの親チャンネルのID
+	ThreadParentID string
+	CreatedAt      time.Time
+	StoredAt       time.Time
 }
@@ -0,0 +1,23 @@
+package domain
+
+import "time"
+
+// PromptTemplateScope はプロンプトテンプレートの適用範囲
+type PromptTemplateScope string
+
+const (
+	PromptTemplateScopeGuild PromptTemplateScope = "guild"
+	PromptTemplateScopeUser  PromptTemplateScope = "user"
+)
+
+// PromptTemplate はギルドまたはユーザー単位で設定されたプロンプトテンプレートの1バージョン
+type PromptTemplate struct {
+	ID             int64
+	Scope          PromptTemplateScope
+	ScopeID        string
+	Version        int
+	SystemTemplate string
+	UserTemplate   string
+	CreatedBy      string
+	CreatedAt      time.Time
+}
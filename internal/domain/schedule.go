@@ -0,0 +1,33 @@
+package domain
+
+import "time"
+
+// ScheduleKind はスケジュールの実行間隔の種類
+type ScheduleKind string
+
+const (
+	// ScheduleKindCron はcron形式の時刻に実行する
+	ScheduleKindCron ScheduleKind = "cron"
+	// ScheduleKindRandom は最小〜最大の間隔からランダムに選んだ時間ごとに実行する
+	ScheduleKindRandom ScheduleKind = "random"
+)
+
+// Schedule はなりきりの定期投稿の設定
+type Schedule struct {
+	ID        int64
+	DiscordID string
+	GuildID   string
+	ChannelID string
+	Kind      ScheduleKind
+	// CronSpec はKindがcronの場合の実行時刻（例: "0 8 * * *"）
+	CronSpec string
+	// MinInterval・MaxInterval はKindがrandomの場合の実行間隔
+	MinInterval time.Duration
+	MaxInterval time.Duration
+	Topic       string
+	// EndsAt 以降は実行せずスケジュールを削除する。nilの場合は無期限
+	EndsAt    *time.Time
+	NextRunAt time.Time
+	LastRunAt *time.Time
+	CreatedAt time.Time
+}
@@ -0,0 +1,39 @@
+package domain
+
+import "time"
+
+// PeriodCount は期間ごとの件数
+type PeriodCount struct {
+	Period time.Time
+	Count  int64
+}
+
+// ChannelCount はチャンネルごとの件数
+type ChannelCount struct {
+	ChannelID string
+	Count     int64
+}
+
+// TermCount は語句ごとの出現回数
+type TermCount struct {
+	Term  string
+	Count int64
+}
+
+// UserStats はユーザーの発言の統計
+type UserStats struct {
+	TotalMessages  int64
+	AverageLength  float64
+	FirstMessageAt *time.Time
+	// Monthly は直近の月ごとの件数（古い順）
+	Monthly  []PeriodCount
+	Channels []ChannelCount
+	// Hours は時刻（0〜23時）ごとの件数
+	Hours    [24]int64
+	TopWords []TermCount
+	TopEmoji []TermCount
+	// LongestStreak は発言した日が連続した最長日数
+	LongestStreak int
+	// CurrentStreak は今日または昨日まで続いている連続日数
+	CurrentStreak int
+}
@@ -8,3 +8,20 @@ type User struct {
 	RegisteredAt time.Time
 	UpdatedAt    time.Time
 }
+
+// UserChangeOp はusersテーブルの変更種別
+type UserChangeOp string
+
+const (
+	UserChangeInsert   UserChangeOp = "INSERT"
+	UserChangeUpdate   UserChangeOp = "UPDATE"
+	UserChangeDelete   UserChangeOp = "DELETE"
+	UserChangeTruncate UserChangeOp = "TRUNCATE"
+)
+
+// UserChange は他のインスタンスから通知されたusersテーブルの変更
+type UserChange struct {
+	Op           UserChangeOp `json:"op"`
+	DiscordID    string       `json:"discord_id"`
+	OldDiscordID string       `json:"old_discord_id"`
+}
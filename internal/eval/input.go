@@ -0,0 +1,46 @@
+package eval
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/chun37/doppelcord/internal/domain"
+)
+
+type inputMessage struct {
+	ChannelID      string    `json:"channel_id"`
+	Content        string    `json:"content"`
+	ReplyToContent string    `json:"reply_to_content"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+// LoadMessages はJSON配列形式のメッセージファイルを読み込み、新しい順に並べて返す。
+// DBに接続できないCI環境での評価に使う
+func LoadMessages(path string) ([]*domain.Message, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var inputs []inputMessage
+	if err := json.Unmarshal(data, &inputs); err != nil {
+		return nil, fmt.Errorf("failed to parse messages %s: %w", path, err)
+	}
+
+	messages := make([]*domain.Message, len(inputs))
+	for i, in := range inputs {
+		messages[i] = &domain.Message{
+			ChannelID:      in.ChannelID,
+			Content:        in.Content,
+			ReplyToContent: in.ReplyToContent,
+			CreatedAt:      in.CreatedAt,
+		}
+	}
+	sort.SliceStable(messages, func(i, j int) bool {
+		return messages[i].CreatedAt.After(messages[j].CreatedAt)
+	})
+	return messages, nil
+}
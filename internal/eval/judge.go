@@ -0,0 +1,75 @@
+package eval
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// JudgeSystemPrompt はLLMによる評価で使うsystemプロンプト
+const JudgeSystemPrompt = `あなたはDiscordユーザーの文体を比較する審査員です。
+「本人の発言」と「生成された発言」を比べ、生成された発言がどれだけ本人らしいかを1〜10で採点してください。
+文体、口調、言葉遣い、絵文字の使い方、文の長さ、話題の傾向を考慮してください。
+出力は次のJSONのみとしてください: {"score": <1〜10の整数>, "reason": "<短い理由>"}`
+
+// JudgeResult はLLMによる評価結果
+type JudgeResult struct {
+	// Raw はLLMがつけた1〜10の点数
+	Raw    int    `json:"raw"`
+	Reason string `json:"reason"`
+	// Score はRawを0〜1に正規化した値
+	Score float64 `json:"score"`
+}
+
+var judgeScorePattern = regexp.MustCompile(`\d+`)
+
+func (r *Runner) judge(ctx context.Context, reference, candidates []string) (*JudgeResult, error) {
+	var sb strings.Builder
+	sb.WriteString("## 本人の発言:\n")
+	for _, text := range reference {
+		sb.WriteString("- ")
+		sb.WriteString(strings.ReplaceAll(text, "\n", " "))
+		sb.WriteString("\n")
+	}
+	sb.WriteString("\n## 生成された発言:\n")
+	for _, text := range candidates {
+		sb.WriteString("- ")
+		sb.WriteString(strings.ReplaceAll(text, "\n", " "))
+		sb.WriteString("\n")
+	}
+
+	content, err := r.client.ChatWithSystem(ctx, JudgeSystemPrompt, sb.String())
+	if err != nil {
+		return nil, err
+	}
+	return parseJudge(content)
+}
+
+func parseJudge(content string) (*JudgeResult, error) {
+	var result struct {
+		Score  int    `json:"score"`
+		Reason string `json:"reason"`
+	}
+
+	// JSON以外の前置きが付くことがあるため、最初の{から最後の}までを取り出す
+	start, end := strings.Index(content, "{"), strings.LastIndex(content, "}")
+	if start < 0 || end <= start || json.Unmarshal([]byte(content[start:end+1]), &result) != nil {
+		m := judgeScorePattern.FindString(content)
+		if m == "" {
+			return nil, fmt.Errorf("no score in judge response: %q", content)
+		}
+		result.Score, _ = strconv.Atoi(m)
+	}
+
+	if result.Score < 1 || result.Score > 10 {
+		return nil, fmt.Errorf("judge score out of range: %d", result.Score)
+	}
+	return &JudgeResult{
+		Raw:    result.Score,
+		Reason: result.Reason,
+		Score:  float64(result.Score-1) / 9,
+	}, nil
+}
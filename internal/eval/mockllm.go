@@ -0,0 +1,81 @@
+package eval
+
+import (
+	"encoding/json"
+	"hash/fnv"
+	"io"
+	"net/http"
+	"strings"
+	"sync/atomic"
+
+	"github.com/chun37/doppelcord/internal/llm"
+)
+
+// NewMockLLMHandler はCI向けのOpenAI互換モックAPIを返す。
+// 外部APIを呼ばずに再現性のある評価ができるよう、リクエスト内容のハッシュで
+// プロンプト中の履歴から1行を選んで返す。同じプロンプトでも呼び出し順に応じて異なる行を返す。
+// 評価用のリクエストには固定の点数を返す
+func NewMockLLMHandler() http.Handler {
+	var calls atomic.Uint32
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		var req llm.ChatRequest
+		if err := json.Unmarshal(body, &req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		content := mockReply(req, body, calls.Add(1))
+
+		resp := llm.ChatResponse{
+			ID: "mock",
+			Choices: []llm.Choice{{
+				Message:      llm.ChatMessage{Role: "assistant", Content: content},
+				FinishReason: "stop",
+			}},
+			Usage: &llm.Usage{
+				PromptTokens:     len(body) / 4,
+				CompletionTokens: len(content) / 4,
+				TotalTokens:      (len(body) + len(content)) / 4,
+			},
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(resp)
+	})
+}
+
+func mockReply(req llm.ChatRequest, body []byte, call uint32) string {
+	var lines []string
+	for _, msg := range req.Messages {
+		if msg.Role == "system" && msg.Content == JudgeSystemPrompt {
+			return `{"score": 5, "reason": "mock"}`
+		}
+		if msg.Role != "system" && msg.Role != "assistant" {
+			continue
+		}
+		for _, line := range strings.Split(msg.Content, "\n") {
+			line = strings.TrimSpace(line)
+			if line == "" || line == "---" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, "- ") {
+				continue
+			}
+			lines = append(lines, line)
+		}
+	}
+	if len(lines) == 0 {
+		return "mock response"
+	}
+
+	h := fnv.New32a()
+	h.Write(body)
+	return lines[int((h.Sum32()+call)%uint32(len(lines)))]
+}
@@ -0,0 +1,57 @@
+package eval
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// Report は評価結果。プロンプトのバージョン間でJSONを比較できる
+type Report struct {
+	Label          string       `json:"label"`
+	Model          string       `json:"model"`
+	Strategy       string       `json:"strategy"`
+	UserID         string       `json:"user_id"`
+	GeneratedAt    time.Time    `json:"generated_at"`
+	HistoryCount   int          `json:"history_count"`
+	HoldoutCount   int          `json:"holdout_count"`
+	CandidateCount int          `json:"candidate_count"`
+	FailedCount    int          `json:"failed_count"`
+	Score          float64      `json:"score"`
+	Style          StyleMetrics `json:"style"`
+	Judge          *JudgeResult `json:"judge,omitempty"`
+	Reference      []string     `json:"reference"`
+	Candidates     []string     `json:"candidates"`
+}
+
+// Write はレポートをJSONで書き出す
+func (r *Report) Write(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.SetEscapeHTML(false)
+	return enc.Encode(r)
+}
+
+// ReadReport はJSONファイルからレポートを読み込む
+func ReadReport(path string) (*Report, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var r Report
+	if err := json.Unmarshal(data, &r); err != nil {
+		return nil, fmt.Errorf("failed to parse report %s: %w", path, err)
+	}
+	return &r, nil
+}
+
+// CheckRegression はベースラインからのスコア低下がmaxDropを超えていればエラーを返す
+func (r *Report) CheckRegression(baseline *Report, maxDrop float64) error {
+	if drop := baseline.Score - r.Score; drop > maxDrop {
+		return fmt.Errorf("score regressed from %.4f (%s) to %.4f (%s), exceeding allowed drop %.4f",
+			baseline.Score, baseline.Label, r.Score, r.Label, maxDrop)
+	}
+	return nil
+}
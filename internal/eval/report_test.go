@@ -0,0 +1,29 @@
+package eval
+
+import "testing"
+
+func TestCheckRegression(t *testing.T) {
+	baseline := &Report{Label: "v1", Score: 0.75}
+	tests := []struct {
+		name    string
+		score   float64
+		maxDrop float64
+		wantErr bool
+	}{
+		{name: "improved", score: 0.875, maxDrop: 0.125, wantErr: false},
+		{name: "unchanged", score: 0.75, maxDrop: 0, wantErr: false},
+		{name: "within allowed drop", score: 0.625, maxDrop: 0.25, wantErr: false},
+		{name: "exactly allowed drop", score: 0.5, maxDrop: 0.25, wantErr: false},
+		{name: "exceeds allowed drop", score: 0.375, maxDrop: 0.25, wantErr: true},
+		{name: "any drop with zero tolerance", score: 0.5, maxDrop: 0, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report := &Report{Label: "v2", Score: tt.score}
+			err := report.CheckRegression(baseline, tt.maxDrop)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("CheckRegression() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,139 @@
+package eval
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/chun37/doppelcord/internal/domain"
+	"github.com/chun37/doppelcord/internal/llm"
+	"github.com/chun37/doppelcord/internal/prompt"
+)
+
+// ChatClient は評価で使うLLMクライアント
+type ChatClient interface {
+	ChatWithSystem(ctx context.Context, systemPrompt, userPrompt string) (string, error)
+	Complete(ctx context.Context, messages []llm.ChatMessage, params llm.Params) (*llm.Completion, error)
+}
+
+// Options は評価の設定
+type Options struct {
+	// Label は比較用のプロンプトバージョン名
+	Label string
+	// Model はレポートに記録するモデル名
+	Model string
+	// Holdout は評価用に取り分ける直近のメッセージ数
+	Holdout int
+	// Candidates は生成する候補数
+	Candidates int
+	// MaxHistory はプロンプトに使う履歴の最大件数
+	MaxHistory int
+	// MaxPromptChars はプロンプトに含める履歴の最大文字数
+	MaxPromptChars int
+	// Strategy は発言履歴をLLMに渡す形式（空の場合はprompt.StrategyTemplate）
+	Strategy prompt.Strategy
+	// Judge がtrueの場合はLLMによる評価も行う
+	Judge bool
+	// Template は評価するプロンプトテンプレート
+	Template prompt.Template
+}
+
+// Runner は保存済みメッセージを使ってなりきりの再現度を評価する
+type Runner struct {
+	client ChatClient
+	opts   Options
+}
+
+// NewRunner は新しいRunnerを生成
+func NewRunner(client ChatClient, opts Options) *Runner {
+	return &Runner{client: client, opts: opts}
+}
+
+// Run はmessages（新しい順）の直近Holdout件を取り分け、残りの履歴から候補を生成して比較する
+func (r *Runner) Run(ctx context.Context, userID string, messages []*domain.Message) (*Report, error) {
+	if len(messages) <= r.opts.Holdout {
+		return nil, fmt.Errorf("need more than %d messages, got %d", r.opts.Holdout, len(messages))
+	}
+
+	heldOut := messages[:r.opts.Holdout]
+	history := messages[r.opts.Holdout:]
+	if len(history) > r.opts.MaxHistory {
+		history = history[:r.opts.MaxHistory]
+	}
+
+	reference := make([]string, len(heldOut))
+	for i, msg := range heldOut {
+		reference[i] = msg.Content
+	}
+
+	// ボットと同じく、他のユーザーへの返信は戦略に応じて会話例または会話の一部として含める
+	var replies []*domain.Message
+	for _, msg := range history {
+		if msg.ReplyToContent != "" {
+			replies = append(replies, msg)
+		}
+	}
+	var data prompt.Data
+	turns := prompt.ApplyHistory(r.opts.Strategy, &data, history, replies, r.opts.MaxPromptChars, r.opts.MaxPromptChars/4)
+	systemPrompt, userPrompt, err := r.opts.Template.Render(data)
+	if err != nil {
+		return nil, err
+	}
+	chat := prompt.ComposeChat(systemPrompt, turns, userPrompt)
+
+	candidates := make([]string, 0, r.opts.Candidates)
+	var failures int
+	for i := 0; i < r.opts.Candidates; i++ {
+		completion, err := r.client.Complete(ctx, chat, llm.Params{})
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			log.Printf("Error generating candidate %d: %v", i+1, err)
+			failures++
+			continue
+		}
+		candidates = append(candidates, completion.Content)
+	}
+	if len(candidates) == 0 {
+		return nil, errors.New("all candidate generations failed")
+	}
+
+	report := &Report{
+		Label:          r.opts.Label,
+		Model:          r.opts.Model,
+		Strategy:       string(strategyOrDefault(r.opts.Strategy)),
+		UserID:         userID,
+		GeneratedAt:    time.Now(),
+		HistoryCount:   len(history),
+		HoldoutCount:   len(heldOut),
+		CandidateCount: len(candidates),
+		FailedCount:    failures,
+		Style:          CompareStyle(reference, candidates),
+		Reference:      reference,
+		Candidates:     candidates,
+	}
+	report.Score = report.Style.Mean()
+
+	if r.opts.Judge {
+		judge, err := r.judge(ctx, reference, candidates)
+		if err != nil {
+			log.Printf("Error running LLM judge: %v", err)
+		} else {
+			report.Judge = judge
+			report.Score = (report.Style.Mean()*5 + judge.Score) / 6
+		}
+	}
+
+	return report, nil
+}
+
+// strategyOrDefault は未指定の戦略をprompt.StrategyTemplateとして扱う
+func strategyOrDefault(s prompt.Strategy) prompt.Strategy {
+	if s == "" {
+		return prompt.StrategyTemplate
+	}
+	return s
+}
@@ -0,0 +1,193 @@
+package eval
+
+import (
+	"math"
+
+	"github.com/chun37/doppelcord/internal/style"
+)
+
+// StyleMetric は参照（実際の発言）と候補（生成文）の特徴量と、その類似度スコア（0〜1）
+type StyleMetric struct {
+	Reference float64 `json:"reference"`
+	Candidate float64 `json:"candidate"`
+	Score     float64 `json:"score"`
+}
+
+// LengthMetric は文字数分布の比較結果
+type LengthMetric struct {
+	Reference LengthStats `json:"reference"`
+	Candidate LengthStats `json:"candidate"`
+	// KS は2つの分布のコルモゴロフ・スミルノフ統計量
+	KS    float64 `json:"ks"`
+	Score float64 `json:"score"`
+}
+
+// LengthStats は文字数の要約統計量
+type LengthStats struct {
+	Mean   float64 `json:"mean"`
+	Median float64 `json:"median"`
+	StdDev float64 `json:"stddev"`
+}
+
+// DistributionMetric は記号や文末表現などの出現分布の比較結果
+type DistributionMetric struct {
+	ReferenceTop []string `json:"reference_top"`
+	CandidateTop []string `json:"candidate_top"`
+	// Score は出現頻度ベクトルのコサイン類似度
+	Score float64 `json:"score"`
+}
+
+// StyleMetrics は文体メトリクス一式
+type StyleMetrics struct {
+	CharNGram      StyleMetric        `json:"char_ngram"`
+	EmojiRate      StyleMetric        `json:"emoji_rate"`
+	Length         LengthMetric       `json:"length"`
+	Punctuation    DistributionMetric `json:"punctuation"`
+	SentenceEnding DistributionMetric `json:"sentence_ending"`
+}
+
+// Mean は各メトリクスのスコアの平均を返す
+func (m StyleMetrics) Mean() float64 {
+	return (m.CharNGram.Score + m.EmojiRate.Score + m.Length.Score + m.Punctuation.Score + m.SentenceEnding.Score) / 5
+}
+
+const topN = 10
+
+// CompareStyle は参照と候補の文体メトリクスを計算する
+func CompareStyle(reference, candidates []string) StyleMetrics {
+	return StyleMetrics{
+		CharNGram:      compareNGrams(reference, candidates),
+		EmojiRate:      compareRates(style.EmojiRate(reference), style.EmojiRate(candidates)),
+		Length:         compareLengths(reference, candidates),
+		Punctuation:    compareDistributions(style.PunctuationCounts(reference), style.PunctuationCounts(candidates)),
+		SentenceEnding: compareDistributions(style.EndingCounts(reference), style.EndingCounts(candidates)),
+	}
+}
+
+// compareNGrams は文字2-gram・3-gramの重なりをF1で評価する。
+// Referenceは参照側の適合率、Candidateは候補側の適合率を表す
+func compareNGrams(reference, candidates []string) StyleMetric {
+	ref := ngramCounts(reference, 2, 3)
+	cand := ngramCounts(candidates, 2, 3)
+
+	overlap, refTotal, candTotal := 0, 0, 0
+	for g, n := range cand {
+		overlap += min(n, ref[g])
+		candTotal += n
+	}
+	for _, n := range ref {
+		refTotal += n
+	}
+	if refTotal == 0 || candTotal == 0 {
+		return StyleMetric{}
+	}
+
+	precision := float64(overlap) / float64(candTotal)
+	recall := float64(overlap) / float64(refTotal)
+	score := 0.0
+	if precision+recall > 0 {
+		score = 2 * precision * recall / (precision + recall)
+	}
+	return StyleMetric{Reference: recall, Candidate: precision, Score: score}
+}
+
+func ngramCounts(texts []string, sizes ...int) map[string]int {
+	counts := make(map[string]int)
+	for _, text := range texts {
+		runes := []rune(text)
+		for _, n := range sizes {
+			for i := 0; i+n <= len(runes); i++ {
+				counts[string(runes[i:i+n])]++
+			}
+		}
+	}
+	return counts
+}
+
+func compareRates(ref, cand float64) StyleMetric {
+	score := 1.0
+	if m := math.Max(ref, cand); m > 0 {
+		score = 1 - math.Abs(ref-cand)/m
+	}
+	return StyleMetric{Reference: ref, Candidate: cand, Score: score}
+}
+
+func compareLengths(reference, candidates []string) LengthMetric {
+	ref := style.Lengths(reference)
+	cand := style.Lengths(candidates)
+	ks := ksStatistic(ref, cand)
+	return LengthMetric{
+		Reference: summarize(ref),
+		Candidate: summarize(cand),
+		KS:        ks,
+		Score:     1 - ks,
+	}
+}
+
+func summarize(sorted []float64) LengthStats {
+	if len(sorted) == 0 {
+		return LengthStats{}
+	}
+	sum := 0.0
+	for _, v := range sorted {
+		sum += v
+	}
+	mean := sum / float64(len(sorted))
+
+	variance := 0.0
+	for _, v := range sorted {
+		variance += (v - mean) * (v - mean)
+	}
+	variance /= float64(len(sorted))
+
+	median := sorted[len(sorted)/2]
+	if len(sorted)%2 == 0 {
+		median = (sorted[len(sorted)/2-1] + sorted[len(sorted)/2]) / 2
+	}
+	return LengthStats{Mean: mean, Median: median, StdDev: math.Sqrt(variance)}
+}
+
+// ksStatistic はソート済みの2標本の経験分布関数の最大差を返す
+func ksStatistic(a, b []float64) float64 {
+	if len(a) == 0 || len(b) == 0 {
+		return 1
+	}
+	i, j, d := 0, 0, 0.0
+	for i < len(a) && j < len(b) {
+		x := math.Min(a[i], b[j])
+		for i < len(a) && a[i] <= x {
+			i++
+		}
+		for j < len(b) && b[j] <= x {
+			j++
+		}
+		d = math.Max(d, math.Abs(float64(i)/float64(len(a))-float64(j)/float64(len(b))))
+	}
+	return d
+}
+
+func compareDistributions(ref, cand map[string]int) DistributionMetric {
+	return DistributionMetric{
+		ReferenceTop: style.Top(ref, topN),
+		CandidateTop: style.Top(cand, topN),
+		Score:        cosine(ref, cand),
+	}
+}
+
+func cosine(a, b map[string]int) float64 {
+	if len(a) == 0 && len(b) == 0 {
+		return 1
+	}
+	dot, na, nb := 0.0, 0.0, 0.0
+	for k, v := range a {
+		dot += float64(v * b[k])
+		na += float64(v * v)
+	}
+	for _, v := range b {
+		nb += float64(v * v)
+	}
+	if na == 0 || nb == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(na) * math.Sqrt(nb))
+}
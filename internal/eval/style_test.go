@@ -0,0 +1,161 @@
+package eval
+
+import (
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/chun37/doppelcord/internal/style"
+)
+
+const epsilon = 1e-9
+
+func almostEqual(a, b float64) bool {
+	return math.Abs(a-b) < epsilon
+}
+
+func TestKSStatistic(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b []float64
+		want float64
+	}{
+		{name: "identical", a: []float64{1, 2, 3}, b: []float64{1, 2, 3}, want: 0},
+		{name: "shifted", a: []float64{1, 2, 3}, b: []float64{2, 3, 4}, want: 1.0 / 3},
+		{name: "disjoint", a: []float64{1, 2}, b: []float64{3, 4}, want: 1},
+		{name: "different sizes", a: []float64{1, 2, 3, 4}, b: []float64{2, 4}, want: 0.25},
+		{name: "empty", a: nil, b: []float64{1}, want: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ksStatistic(tt.a, tt.b); !almostEqual(got, tt.want) {
+				t.Errorf("ksStatistic() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompareLengths(t *testing.T) {
+	got := compareLengths([]string{"あ", "ああ", "あああ", "ああああ"}, []string{"ああ", "ああああ"})
+	want := LengthMetric{
+		Reference: LengthStats{Mean: 2.5, Median: 2.5, StdDev: math.Sqrt(1.25)},
+		Candidate: LengthStats{Mean: 3, Median: 3, StdDev: 1},
+		KS:        0.25,
+		Score:     0.75,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("compareLengths() = %+v, want %+v", got, want)
+	}
+}
+
+func TestCosine(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b map[string]int
+		want float64
+	}{
+		{name: "identical", a: map[string]int{"ね": 2, "よ": 1}, b: map[string]int{"ね": 2, "よ": 1}, want: 1},
+		{name: "partial", a: map[string]int{"ね": 1, "よ": 1}, b: map[string]int{"ね": 1}, want: 1 / math.Sqrt2},
+		{name: "orthogonal", a: map[string]int{"ね": 1}, b: map[string]int{"よ": 1}, want: 0},
+		{name: "both empty", a: map[string]int{}, b: map[string]int{}, want: 1},
+		{name: "one empty", a: map[string]int{"ね": 1}, b: map[string]int{}, want: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := cosine(tt.a, tt.b); !almostEqual(got, tt.want) {
+				t.Errorf("cosine() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSentenceEnding(t *testing.T) {
+	reference := []string{"そうだね！", "いいね😊 ", "<:ok:123>行こうね"}
+	candidates := []string{"だよね", "まじか"}
+
+	got := compareDistributions(style.EndingCounts(reference), style.EndingCounts(candidates))
+	// 参照は「ね！」1・「いね」1・「うね」1、候補は「よね」1・「じか」1で共通する文末はない
+	want := DistributionMetric{
+		ReferenceTop: []string{"いね", "うね", "ね！"},
+		CandidateTop: []string{"じか", "よね"},
+		Score:        0,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("compareDistributions() = %+v, want %+v", got, want)
+	}
+}
+
+func TestCompareNGrams(t *testing.T) {
+	tests := []struct {
+		name       string
+		reference  []string
+		candidates []string
+		want       StyleMetric
+	}{
+		{
+			name:       "identical",
+			reference:  []string{"abc"},
+			candidates: []string{"abc"},
+			want:       StyleMetric{Reference: 1, Candidate: 1, Score: 1},
+		},
+		{
+			// 参照は ab・bc・abc、候補は ab・bd・abd で、重なりは ab のみ
+			name:       "partial",
+			reference:  []string{"abc"},
+			candidates: []string{"abd"},
+			want:       StyleMetric{Reference: 1.0 / 3, Candidate: 1.0 / 3, Score: 1.0 / 3},
+		},
+		{
+			// 参照は ab・bc・abc（3件）、候補は ab・bc・cd・abc・bcd（5件）で、重なりは3件
+			name:       "different precision and recall",
+			reference:  []string{"abc"},
+			candidates: []string{"abcd"},
+			want:       StyleMetric{Reference: 1, Candidate: 0.6, Score: 0.75},
+		},
+		{
+			name:       "no candidates",
+			reference:  []string{"abc"},
+			candidates: nil,
+			want:       StyleMetric{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := compareNGrams(tt.reference, tt.candidates)
+			if !almostEqual(got.Reference, tt.want.Reference) || !almostEqual(got.Candidate, tt.want.Candidate) || !almostEqual(got.Score, tt.want.Score) {
+				t.Errorf("compareNGrams() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompareEmojiRate(t *testing.T) {
+	tests := []struct {
+		name       string
+		reference  []string
+		candidates []string
+		want       StyleMetric
+	}{
+		{
+			// 参照は2件で絵文字3個（カスタム絵文字を含む）、候補は2件で1個
+			name:       "different rates",
+			reference:  []string{"😊😊", "hi <:smile:123>"},
+			candidates: []string{"ok 👍", "ok"},
+			want:       StyleMetric{Reference: 1.5, Candidate: 0.5, Score: 1.0 / 3},
+		},
+		{
+			name:       "no emoji",
+			reference:  []string{"hi"},
+			candidates: []string{"hello"},
+			want:       StyleMetric{Reference: 0, Candidate: 0, Score: 1},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := CompareStyle(tt.reference, tt.candidates).EmojiRate
+			if !almostEqual(got.Reference, tt.want.Reference) || !almostEqual(got.Candidate, tt.want.Candidate) || !almostEqual(got.Score, tt.want.Score) {
+				t.Errorf("EmojiRate = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
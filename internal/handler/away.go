@@ -0,0 +1,231 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+
+	"github.com/chun37/doppelcord/internal/domain"
+	"github.com/chun37/doppelcord/internal/llm"
+	"github.com/chun37/doppelcord/internal/metrics"
+	"github.com/chun37/doppelcord/internal/usage"
+)
+
+const (
+	// awayReplyCooldown は同じチャンネルで同じユーザーの代わりに自動応答する間隔
+	awayReplyCooldown = 10 * time.Minute
+	// awayMaxRepliesPerMessage は1つのメッセージに対して自動応答する最大人数
+	awayMaxRepliesPerMessage = 2
+	// awayLimiterMaxEntries を超えたら期限切れの記録を削除する
+	awayLimiterMaxEntries = 1000
+
+	awayInstruction = "今あなたは不在で、本人の代わりに自動で返信しています。不在であることを自然に伝えつつ、いつもの口調で短く返信してください。"
+)
+
+// awayLimiter はチャンネルと不在ユーザーの組ごとに自動応答の間隔を制限する
+type awayLimiter struct {
+	mu   sync.Mutex
+	last map[string]time.Time
+}
+
+func newAwayLimiter() *awayLimiter {
+	return &awayLimiter{last: make(map[string]time.Time)}
+}
+
+// allow は自動応答してよければ記録してtrueを返す
+func (l *awayLimiter) allow(channelID, userID string, now time.Time) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	key := channelID + ":" + userID
+	if last, ok := l.last[key]; ok && now.Sub(last) < awayReplyCooldown {
+		return false
+	}
+	if len(l.last) >= awayLimiterMaxEntries {
+		for k, t := range l.last {
+			if now.Sub(t) >= awayReplyCooldown {
+				delete(l.last, k)
+			}
+		}
+	}
+	l.last[key] = now
+	return true
+}
+
+func (h *InteractionHandler) handleAway(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate) {
+	options := i.ApplicationCommandData().Options
+	if len(options) == 0 {
+		return
+	}
+
+	userID := interactionUser(i).ID
+	switch sub := options[0]; sub.Name {
+	case "on":
+		h.handleAwayOn(ctx, s, i, userID, sub.Options)
+	case "off":
+		cleared, err := h.awayRepo.Clear(ctx, userID)
+		if err != nil {
+			log.Printf("Error clearing away status: %v", err)
+			h.respondEphemeral(s, i, "不在設定の解除に失敗しました。")
+			return
+		}
+		if !cleared {
+			h.respondEphemeral(s, i, "不在設定はされていません。")
+			return
+		}
+		h.respondEphemeral(s, i, "不在設定を解除しました。おかえりなさい！")
+	}
+}
+
+func (h *InteractionHandler) handleAwayOn(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate, userID string, options []*discordgo.ApplicationCommandInteractionDataOption) {
+	status := &domain.AwayStatus{DiscordID: userID}
+	var until string
+	for _, opt := range options {
+		switch opt.Name {
+		case "until":
+			until = opt.StringValue()
+		case "note":
+			status.Note = strings.TrimSpace(opt.StringValue())
+		}
+	}
+
+	date, err := parseDate(until, h.promptConfig.Location)
+	if err != nil {
+		h.respondEphemeral(s, i, "until は YYYY-MM-DD の形式で指定してください。")
+		return
+	}
+	// 指定日の終わりまで不在とする
+	status.Until = date.AddDate(0, 0, 1)
+	if !status.Until.After(time.Now()) {
+		h.respondEphemeral(s, i, "until には今日以降の日付を指定してください。")
+		return
+	}
+
+	registered, err := h.userRepo.IsRegistered(ctx, userID)
+	if err != nil {
+		log.Printf("Error checking registration: %v", err)
+		h.respondEphemeral(s, i, "登録状況の確認に失敗しました。")
+		return
+	}
+	if !registered {
+		h.respondEphemeral(s, i, "先に /register で登録してください。")
+		return
+	}
+
+	if err := h.awayRepo.Set(ctx, status); err != nil {
+		log.Printf("Error saving away status: %v", err)
+		h.respondEphemeral(s, i, "不在設定の保存に失敗しました。")
+		return
+	}
+	h.respondEphemeral(s, i, fmt.Sprintf("%sまで不在に設定しました。その間にメンションされると、なりきりが自動応答として返信します（同じチャンネルでは%d分に1回まで）。", discordTimestamp(status.Until, "f"), int(awayReplyCooldown.Minutes())))
+}
+
+// RespondAway はメッセージで言及されたユーザーのうち不在中のユーザーについて、
+// 言及したメッセージへの返信をなりきりで生成し、自動応答であることを明示して投稿する
+func (h *InteractionHandler) RespondAway(ctx context.Context, s *discordgo.Session, m *discordgo.MessageCreate) {
+	if m.GuildID == "" || m.Author.Bot || len(m.Mentions) == 0 {
+		return
+	}
+
+	mentioned := make([]string, 0, len(m.Mentions))
+	users := make(map[string]*discordgo.User, len(m.Mentions))
+	for _, u := range m.Mentions {
+		if u.Bot || u.ID == m.Author.ID {
+			continue
+		}
+		mentioned = append(mentioned, u.ID)
+		users[u.ID] = u
+	}
+	if len(mentioned) == 0 {
+		return
+	}
+
+	now := time.Now()
+	statuses, err := h.awayRepo.FindActive(ctx, mentioned, now)
+	if err != nil {
+		log.Printf("Error fetching away statuses: %v", err)
+		return
+	}
+
+	replies := 0
+	for _, status := range statuses {
+		if replies >= awayMaxRepliesPerMessage {
+			break
+		}
+		if !h.awayLimiter.allow(m.ChannelID, status.DiscordID, now) {
+			continue
+		}
+		if err := h.replyAway(ctx, s, m, users[status.DiscordID], status); err != nil {
+			log.Printf("Error replying as away user %s: %v", status.DiscordID, err)
+			continue
+		}
+		replies++
+	}
+}
+
+func (h *InteractionHandler) replyAway(ctx context.Context, s *discordgo.Session, m *discordgo.MessageCreate, user *discordgo.User, status *domain.AwayStatus) error {
+	if err := h.usage.Check(ctx, user.ID); err != nil {
+		var limitErr *usage.LimitError
+		if errors.As(err, &limitErr) {
+			metrics.QuotaRejections.Inc(string(limitErr.Reason))
+			fmt.Printf("Skipping away reply for %s: %s\n", user.ID, limitErr)
+			return nil
+		}
+		return fmt.Errorf("failed to check usage: %w", err)
+	}
+
+	name := h.memberName(s, m.GuildID, user)
+	quote := quoteWithChain(s, m.Message)
+	// メンションは「<@ID>」のままだと伝わらないため名前に置き換える
+	quote.content = m.ContentWithMentionsReplaced()
+
+	instruction := awayInstruction
+	if status.Note != "" {
+		instruction += "（不在の理由: " + status.Note + "）"
+	}
+	chat, err := h.buildPersonaPrompt(ctx, s, personaRequest{
+		userID:      user.ID,
+		guildID:     m.GuildID,
+		channelID:   m.ChannelID,
+		displayName: name,
+		replyTo:     quote,
+		instruction: instruction,
+	})
+	if err != nil {
+		return err
+	}
+
+	gen := &domain.Generation{
+		DiscordID: user.ID,
+		GuildID:   m.GuildID,
+		ChannelID: m.ChannelID,
+		Command:   "away",
+	}
+	completion, err := h.generate(ctx, gen, chat, llm.Params{})
+	if err != nil {
+		return fmt.Errorf("failed to generate: %w", err)
+	}
+
+	_, err = s.ChannelMessageSendComplex(m.ChannelID, &discordgo.MessageSend{
+		Content: fmt.Sprintf("🤖 %s さんは不在のため、なりきりが自動で返信しています（%sに戻る予定）", name, discordTimestamp(status.Until, "R")),
+		Embeds: []*discordgo.MessageEmbed{{
+			Author: &discordgo.MessageEmbedAuthor{
+				Name:    name + "（なりきり・自動応答）",
+				IconURL: user.AvatarURL("64"),
+			},
+			Description: truncateRunes(strings.TrimSpace(completion.Content), maxEmbedDescription),
+		}},
+		Reference:       m.Reference(),
+		AllowedMentions: &discordgo.MessageAllowedMentions{},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to post: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,230 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/bwmarrin/discordgo"
+
+	"github.com/chun37/doppelcord/internal/domain"
+	"github.com/chun37/doppelcord/internal/llm"
+	"github.com/chun37/doppelcord/internal/prompt"
+	"github.com/chun37/doppelcord/internal/style"
+)
+
+const (
+	maxBlendWeight = 10
+	// blendTraitCount は特徴として示す文末表現・記号の数
+	blendTraitCount = 3
+)
+
+// blendSlots はブレンドする対象のオプション名（この順に並べる）
+var blendSlots = []string{"a", "b", "c", "d", "e"}
+
+// blendContributor はブレンドする対象のユーザー
+type blendContributor struct {
+	user   *discordgo.User
+	name   string
+	weight int
+}
+
+func (h *InteractionHandler) handleBlend(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate) {
+	var topic, weightsText string
+	slots := make(map[string]*discordgo.User)
+	for _, opt := range i.ApplicationCommandData().Options {
+		switch opt.Name {
+		case "weights":
+			weightsText = opt.StringValue()
+		case "topic":
+			topic = strings.TrimSpace(opt.StringValue())
+		default:
+			slots[opt.Name] = opt.UserValue(s)
+		}
+	}
+	var users []*discordgo.User
+	for _, name := range blendSlots {
+		if u, ok := slots[name]; ok {
+			users = append(users, u)
+		}
+	}
+
+	weights, err := parseWeights(weightsText, len(users))
+	if err != nil {
+		h.respondEphemeral(s, i, err.Error())
+		return
+	}
+
+	problem, err := h.checkParticipants(ctx, interactionUser(i).ID, users)
+	if err != nil {
+		log.Printf("Error checking blend participants: %v", err)
+		h.respondEphemeral(s, i, "参加者の確認に失敗しました。")
+		return
+	}
+	if problem != "" {
+		h.respondEphemeral(s, i, problem)
+		return
+	}
+	if !h.checkUsage(ctx, s, i) {
+		return
+	}
+
+	err = s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
+	})
+	if err != nil {
+		log.Printf("Error deferring response: %v", err)
+		return
+	}
+
+	contributors := make([]blendContributor, 0, len(users))
+	for n, u := range users {
+		contributors = append(contributors, blendContributor{user: u, name: h.memberName(s, i.GuildID, u), weight: weights[n]})
+	}
+
+	chat, err := h.buildBlendPrompt(ctx, s, i, contributors, topic)
+	if err != nil {
+		log.Printf("Error building blend prompt: %v", err)
+		var pe *promptError
+		if errors.As(err, &pe) {
+			h.editResponse(s, i, pe.message)
+		}
+		return
+	}
+
+	completion, err := h.generate(ctx, newGeneration(i, "blend"), chat, llm.Params{})
+	if err != nil {
+		log.Printf("Error calling LLM API: %v", err)
+		h.editResponse(s, i, "LLM APIの呼び出しに失敗しました。")
+		return
+	}
+
+	names := make([]string, 0, len(contributors))
+	ratios := make([]string, 0, len(contributors))
+	for _, c := range contributors {
+		names = append(names, c.name)
+		ratios = append(ratios, strconv.Itoa(c.weight))
+	}
+	embeds := []*discordgo.MessageEmbed{{
+		Author:      &discordgo.MessageEmbedAuthor{Name: strings.Join(names, " × ") + "（ブレンド）"},
+		Description: truncateRunes(strings.TrimSpace(completion.Content), maxEmbedDescription),
+		Footer:      &discordgo.MessageEmbedFooter{Text: "比率 " + strings.Join(ratios, ":")},
+	}}
+	if _, err := s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{Embeds: &embeds}); err != nil {
+		log.Printf("Error editing response: %v", err)
+	}
+}
+
+// parseWeights は "2,1,1" のような比率をユーザー数ぶん解析する。空の場合はすべて1
+func parseWeights(text string, count int) ([]int, error) {
+	fields := strings.FieldsFunc(text, func(r rune) bool {
+		return r == ',' || r == ':' || r == '、' || unicode.IsSpace(r)
+	})
+	weights := make([]int, count)
+	if len(fields) == 0 {
+		for n := range weights {
+			weights[n] = 1
+		}
+		return weights, nil
+	}
+	if len(fields) != count {
+		return nil, fmt.Errorf("weights にはユーザーの数（%d個）だけ比率を指定してください（例: 2,1,1）。", count)
+	}
+	for n, field := range fields {
+		w, err := strconv.Atoi(field)
+		if err != nil || w < 1 || w > maxBlendWeight {
+			return nil, fmt.Errorf("weights の比率は1〜%dの整数で指定してください。", maxBlendWeight)
+		}
+		weights[n] = w
+	}
+	return weights, nil
+}
+
+// buildBlendPrompt は各ユーザーの履歴を比率に応じて取得し、各ユーザーの文体の特徴を人物像として
+// 組み込んだプロンプトを組み立てる。テンプレートはサーバー単位の設定（なければデフォルト）を使う
+func (h *InteractionHandler) buildBlendPrompt(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate, contributors []blendContributor, topic string) ([]llm.ChatMessage, error) {
+	total := 0
+	for _, c := range contributors {
+		total += c.weight
+	}
+
+	limits := make(map[string]int, len(contributors))
+	for _, c := range contributors {
+		limits[c.user.ID] = max(1, h.promptConfig.MaxMessages*c.weight/total)
+	}
+	messages, err := h.messageRepo.FindByDiscordIDs(ctx, limits)
+	if err != nil {
+		return nil, &promptError{message: "メッセージ履歴の取得に失敗しました。", err: err}
+	}
+	byUser := make(map[string][]*domain.Message, len(contributors))
+	for _, msg := range messages {
+		byUser[msg.DiscordID] = append(byUser[msg.DiscordID], msg)
+	}
+
+	var history, persona strings.Builder
+	var turns []llm.ChatMessage
+	persona.WriteString("複数のユーザーの文体を混ぜ合わせた人物です。次の割合で各ユーザーの特徴を取り入れ、1人の発言として自然にまとめてください。")
+	names := make([]string, 0, len(contributors))
+	for _, c := range contributors {
+		userMessages := byUser[c.user.ID]
+		if len(userMessages) == 0 {
+			return nil, &promptError{message: fmt.Sprintf("%s さんのメッセージ履歴がまだ保存されていません。", c.name)}
+		}
+		names = append(names, c.name)
+
+		texts := make([]string, 0, len(userMessages))
+		for _, msg := range userMessages {
+			texts = append(texts, msg.Content)
+		}
+		fmt.Fprintf(&persona, "\n- %s（%d%%）: %s", c.name, c.weight*100/total, describeStyle(style.ProfileOf(texts, blendTraitCount)))
+
+		// 履歴の文字数も比率で配分する。会話形式ではユーザーごとの会話を順に並べる
+		budget := h.promptConfig.MaxPromptChars * c.weight / total
+		var part prompt.Data
+		turns = append(turns, h.applyHistory(&part, userMessages, nil, budget)...)
+		fmt.Fprintf(&history, "【%s】\n%s", c.name, part.History)
+	}
+
+	resolved, err := h.resolver.Resolve(ctx, i.GuildID, "")
+	if err != nil {
+		return nil, &promptError{message: "プロンプトテンプレートの取得に失敗しました。", err: err}
+	}
+
+	data := prompt.Data{
+		History:     history.String(),
+		Persona:     persona.String(),
+		ChannelName: channelName(s, i.ChannelID),
+		DisplayName: strings.Join(names, "・"),
+		TimeOfDay:   prompt.TimeOfDay(time.Now().In(h.promptConfig.Location)),
+		Topic:       topic,
+	}
+	if h.promptConfig.Strategy == prompt.StrategyChat {
+		data.History = prompt.ChatHistoryNote
+	}
+	systemPrompt, userPrompt, err := resolved.Template.Render(data)
+	if err != nil {
+		return nil, &promptError{message: "プロンプトテンプレートの展開に失敗しました。", err: err}
+	}
+
+	return prompt.ComposeChat(systemPrompt, turns, userPrompt), nil
+}
+
+// describeStyle は文体の特徴を人物像に含める1行の説明にする
+func describeStyle(p style.Profile) string {
+	traits := []string{
+		fmt.Sprintf("平均%.0f文字", p.MeanLength),
+		fmt.Sprintf("絵文字は1発言あたり%.1f個", p.EmojiRate),
+	}
+	if len(p.Endings) > 0 {
+		traits = append(traits, "よく使う文末「"+strings.Join(p.Endings, "」「")+"」")
+	}
+	if len(p.Punctuation) > 0 {
+		traits = append(traits, "よく使う記号「"+strings.Join(p.Punctuation, "」「")+"」")
+	}
+	return strings.Join(traits, "、")
+}
@@ -0,0 +1,144 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math/rand/v2"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+
+	"github.com/chun37/doppelcord/internal/domain"
+	"github.com/chun37/doppelcord/internal/llm"
+	"github.com/chun37/doppelcord/internal/prompt"
+)
+
+// channelVoicePoolFactor は抽出元として取得する直近のメッセージ数（プロンプトに含める件数の倍数）
+const channelVoicePoolFactor = 4
+
+func (h *InteractionHandler) handleChannelVoice(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate) {
+	var topic string
+	for _, opt := range i.ApplicationCommandData().Options {
+		if opt.Name == "topic" {
+			topic = strings.TrimSpace(opt.StringValue())
+		}
+	}
+
+	if !h.checkUsage(ctx, s, i) {
+		return
+	}
+
+	err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
+	})
+	if err != nil {
+		log.Printf("Error deferring response: %v", err)
+		return
+	}
+
+	chat, authors, err := h.buildChannelVoicePrompt(ctx, s, i, topic)
+	if err != nil {
+		log.Printf("Error building channel voice prompt: %v", err)
+		var pe *promptError
+		if errors.As(err, &pe) {
+			h.editResponse(s, i, pe.message)
+		}
+		return
+	}
+
+	completion, err := h.generate(ctx, newGeneration(i, "channel_voice"), chat, llm.Params{})
+	if err != nil {
+		log.Printf("Error calling LLM API: %v", err)
+		h.editResponse(s, i, "LLM APIの呼び出しに失敗しました。")
+		return
+	}
+
+	embeds := []*discordgo.MessageEmbed{{
+		Author:      &discordgo.MessageEmbedAuthor{Name: fmt.Sprintf("#%s の声（なりきり）", channelName(s, i.ChannelID))},
+		Description: truncateRunes(strings.TrimSpace(completion.Content), maxEmbedDescription),
+		Footer:      &discordgo.MessageEmbedFooter{Text: fmt.Sprintf("%d人の発言から生成", authors)},
+	}}
+	if _, err := s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{Embeds: &embeds}); err != nil {
+		log.Printf("Error editing response: %v", err)
+	}
+}
+
+// buildChannelVoicePrompt はチャンネルの直近のメッセージから、発言の利用に同意しているユーザーの発言を無作為に抽出し、
+// チャンネル全体の口調を真似るプロンプトを組み立てる。発言の多いユーザーほど多く抽出される
+func (h *InteractionHandler) buildChannelVoicePrompt(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate, topic string) ([]llm.ChatMessage, int, error) {
+	recent, err := h.messageRepo.FindByChannelID(ctx, i.ChannelID, h.promptConfig.MaxMessages*channelVoicePoolFactor)
+	if err != nil {
+		return nil, 0, &promptError{message: "メッセージ履歴の取得に失敗しました。", err: err}
+	}
+
+	authors := make([]string, 0, len(recent))
+	seen := make(map[string]bool)
+	for _, msg := range recent {
+		if !seen[msg.DiscordID] {
+			seen[msg.DiscordID] = true
+			authors = append(authors, msg.DiscordID)
+		}
+	}
+	allowed, err := h.consentRepo.FilterAllowed(ctx, authors, interactionUser(i).ID)
+	if err != nil {
+		return nil, 0, &promptError{message: "ユーザーの確認に失敗しました。", err: err}
+	}
+	eligible := make(map[string]bool, len(allowed))
+	for _, id := range allowed {
+		eligible[id] = true
+	}
+	pool := make([]*domain.Message, 0, len(recent))
+	for _, msg := range recent {
+		if eligible[msg.DiscordID] {
+			pool = append(pool, msg)
+		}
+	}
+	if len(pool) == 0 {
+		return nil, 0, &promptError{message: "このチャンネルには、なりきりの利用を許可しているユーザーのメッセージ履歴がまだありません。"}
+	}
+
+	messages := sampleMessages(pool, h.promptConfig.MaxMessages)
+	sampled := make(map[string]bool)
+	for _, msg := range messages {
+		sampled[msg.DiscordID] = true
+	}
+
+	resolved, err := h.resolver.Resolve(ctx, i.GuildID, "")
+	if err != nil {
+		return nil, 0, &promptError{message: "プロンプトテンプレートの取得に失敗しました。", err: err}
+	}
+
+	name := channelName(s, i.ChannelID)
+	data := prompt.Data{
+		Persona:     "履歴はこのチャンネルの複数の参加者の発言です。特定の誰かではなく、チャンネル全体に共通する話題・口調・ノリを真似て、このチャンネルにいかにもありそうな発言をしてください。",
+		ChannelName: name,
+		DisplayName: fmt.Sprintf("#%s の常連", name),
+		TimeOfDay:   prompt.TimeOfDay(time.Now().In(h.promptConfig.Location)),
+		Topic:       topic,
+	}
+	turns := h.applyHistory(&data, messages, nil, h.promptConfig.MaxPromptChars)
+	systemPrompt, userPrompt, err := resolved.Template.Render(data)
+	if err != nil {
+		return nil, 0, &promptError{message: "プロンプトテンプレートの展開に失敗しました。", err: err}
+	}
+
+	return prompt.ComposeChat(systemPrompt, turns, userPrompt), len(sampled), nil
+}
+
+// sampleMessages はメッセージから無作為にn件を選び、元の順序（新しい順）のまま返す
+func sampleMessages(messages []*domain.Message, n int) []*domain.Message {
+	if len(messages) <= n {
+		return messages
+	}
+	picked := rand.Perm(len(messages))[:n]
+	slices.Sort(picked)
+	sampled := make([]*domain.Message, 0, n)
+	for _, idx := range picked {
+		sampled = append(sampled, messages[idx])
+	}
+	return sampled
+}
@@ -0,0 +1,74 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func (h *InteractionHandler) handleConsent(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate) {
+	userID := interactionUser(i).ID
+
+	options := i.ApplicationCommandData().Options
+	if len(options) == 0 {
+		allowed, err := h.consentRepo.IsAllowed(ctx, userID)
+		if err != nil {
+			log.Printf("Error fetching consent: %v", err)
+			h.respondEphemeral(s, i, "設定の取得に失敗しました。")
+			return
+		}
+		h.respondEphemeral(s, i, fmt.Sprintf("現在の設定: %s\n`/consent allow:` で変更できます。", consentLabel(allowed)))
+		return
+	}
+
+	allowed := options[0].BoolValue()
+	if err := h.consentRepo.SetAllowed(ctx, userID, allowed); err != nil {
+		log.Printf("Error saving consent: %v", err)
+		h.respondEphemeral(s, i, "設定の保存に失敗しました。")
+		return
+	}
+	h.respondEphemeral(s, i, fmt.Sprintf("設定を変更しました: %s", consentLabel(allowed)))
+}
+
+func consentLabel(allowed bool) string {
+	if allowed {
+		return "他のユーザーがあなたのなりきりを使うことを**許可**しています"
+	}
+	return "他のユーザーがあなたのなりきりを使うことを**許可していません**"
+}
+
+// checkParticipants は実行者以外の参加者が登録済みで、なりきりの利用に同意しているか確認する。
+// 問題があれば実行者に表示するメッセージを返す
+func (h *InteractionHandler) checkParticipants(ctx context.Context, invokerID string, users []*discordgo.User) (string, error) {
+	seen := make(map[string]bool)
+	for _, u := range users {
+		if u.Bot {
+			return "ボットは参加できません。", nil
+		}
+		if seen[u.ID] {
+			return "同じユーザーを複数回指定することはできません。", nil
+		}
+		seen[u.ID] = true
+
+		registered, err := h.userRepo.IsRegistered(ctx, u.ID)
+		if err != nil {
+			return "", err
+		}
+		if !registered {
+			return fmt.Sprintf("<@%s> さんは登録されていません。", u.ID), nil
+		}
+		if u.ID == invokerID {
+			continue
+		}
+		allowed, err := h.consentRepo.IsAllowed(ctx, u.ID)
+		if err != nil {
+			return "", err
+		}
+		if !allowed {
+			return fmt.Sprintf("<@%s> さんはなりきりの利用を許可していません（`/consent allow:True` で許可できます）。", u.ID), nil
+		}
+	}
+	return "", nil
+}
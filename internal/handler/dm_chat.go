@@ -0,0 +1,193 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"log"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+
+	"github.com/chun37/doppelcord/internal/domain"
+	"github.com/chun37/doppelcord/internal/llm"
+	"github.com/chun37/doppelcord/internal/metrics"
+	"github.com/chun37/doppelcord/internal/usage"
+)
+
+// dmInstruction はDMでの会話でsystemプロンプトの末尾に追加する指示
+const dmInstruction = `
+
+## 会話
+あなたは今、本人とDMで1対1で会話しています。相手は本人なので、友達と話すようにいつもの口調で返答してください。
+返答のメッセージ本文のみを出力してください。`
+
+const dmHelp = "このDMでは、あなたの発言履歴から作ったなりきりと会話できます。\n" +
+	"- `reset`（または `リセット`）: 会話の文脈をリセット\n" +
+	"- `help`（または `ヘルプ`）: この説明を表示\n" +
+	"DMのメッセージは既定では発言履歴に保存されません。"
+
+// dmSession はDMチャンネルごとの会話の文脈
+type dmSession struct {
+	// turns はuserとassistantのメッセージを交互に古い順で保持する
+	turns      []llm.ChatMessage
+	lastActive time.Time
+}
+
+// dmSessions はDMチャンネルごとの会話の文脈を期限付きで保持する
+type dmSessions struct {
+	mu       sync.Mutex
+	sessions map[string]*dmSession
+	maxTurns int
+	ttl      time.Duration
+}
+
+func newDMSessions(cfg DMConfig) *dmSessions {
+	return &dmSessions{sessions: make(map[string]*dmSession), maxTurns: cfg.MaxTurns, ttl: cfg.SessionTTL}
+}
+
+// history はチャンネルの会話の文脈を返す。期限切れの場合はnil
+func (d *dmSessions) history(channelID string, now time.Time) []llm.ChatMessage {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	session, ok := d.sessions[channelID]
+	if !ok || now.Sub(session.lastActive) > d.ttl {
+		delete(d.sessions, channelID)
+		return nil
+	}
+	return append([]llm.ChatMessage(nil), session.turns...)
+}
+
+// append は1回のやり取りを追加し、古いやり取りをmaxTurnsまで切り詰める
+func (d *dmSessions) append(channelID, userContent, reply string, now time.Time) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for id, session := range d.sessions {
+		if now.Sub(session.lastActive) > d.ttl {
+			delete(d.sessions, id)
+		}
+	}
+
+	session, ok := d.sessions[channelID]
+	if !ok {
+		session = &dmSession{}
+		d.sessions[channelID] = session
+	}
+	session.turns = append(session.turns,
+		llm.ChatMessage{Role: "user", Content: userContent},
+		llm.ChatMessage{Role: "assistant", Content: reply},
+	)
+	if over := len(session.turns) - d.maxTurns*2; over > 0 {
+		session.turns = session.turns[over:]
+	}
+	session.lastActive = now
+}
+
+// reset はチャンネルの会話の文脈を削除し、文脈があった場合はtrueを返す
+func (d *dmSessions) reset(channelID string) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	_, ok := d.sessions[channelID]
+	delete(d.sessions, channelID)
+	return ok
+}
+
+// RespondDM は登録ユーザーからのDMに、本人の履歴から作ったなりきりとして返信する。
+// DMチャンネルごとに直近のやり取りを文脈として保持する
+func (h *InteractionHandler) RespondDM(ctx context.Context, s *discordgo.Session, m *discordgo.MessageCreate) {
+	content := strings.TrimSpace(m.Content)
+	if content == "" {
+		return
+	}
+
+	registered, err := h.userRepo.IsRegistered(ctx, m.Author.ID)
+	if err != nil {
+		log.Printf("Error checking user registration: %v", err)
+		return
+	}
+	if !registered {
+		h.sendDM(s, m.ChannelID, "なりきりと会話するには、先にサーバーで /register を実行して登録してください。")
+		return
+	}
+
+	switch strings.ToLower(content) {
+	case "reset", "リセット":
+		if h.dmSessions.reset(m.ChannelID) {
+			h.sendDM(s, m.ChannelID, "会話をリセットしました。")
+		} else {
+			h.sendDM(s, m.ChannelID, "リセットする会話はありません。")
+		}
+		return
+	case "help", "ヘルプ":
+		h.sendDM(s, m.ChannelID, dmHelp)
+		return
+	}
+
+	if err := h.usage.Check(ctx, m.Author.ID); err != nil {
+		var limitErr *usage.LimitError
+		if !errors.As(err, &limitErr) {
+			log.Printf("Error checking usage: %v", err)
+			h.sendDM(s, m.ChannelID, "利用状況の確認に失敗しました。")
+			return
+		}
+		metrics.QuotaRejections.Inc(string(limitErr.Reason))
+		h.sendDM(s, m.ChannelID, limitMessage(limitErr))
+		return
+	}
+
+	if err := s.ChannelTyping(m.ChannelID); err != nil {
+		log.Printf("Error sending typing indicator: %v", err)
+	}
+
+	chat, err := h.buildPersonaPrompt(ctx, s, personaRequest{
+		userID:      m.Author.ID,
+		channelID:   m.ChannelID,
+		displayName: displayName(nil, m.Author),
+	})
+	if err != nil {
+		log.Printf("Error building DM prompt: %v", err)
+		var pe *promptError
+		if errors.As(err, &pe) {
+			h.sendDM(s, m.ChannelID, pe.message)
+		}
+		return
+	}
+
+	// テンプレートのsystemプロンプト（と会話形式の履歴）に、DMでの会話の文脈と今回のメッセージを続ける
+	now := time.Now()
+	messages := slices.Clone(chat[:len(chat)-1])
+	messages[0].Content += dmInstruction
+	messages = append(messages, h.dmSessions.history(m.ChannelID, now)...)
+	messages = append(messages, llm.ChatMessage{Role: "user", Content: content})
+
+	gen := &domain.Generation{
+		DiscordID: m.Author.ID,
+		ChannelID: m.ChannelID,
+		Command:   "dm",
+	}
+	completion, err := h.generate(ctx, gen, messages, llm.Params{})
+	if err != nil {
+		log.Printf("Error calling LLM API: %v", err)
+		h.sendDM(s, m.ChannelID, "LLM APIの呼び出しに失敗しました。")
+		return
+	}
+
+	reply := strings.TrimSpace(completion.Content)
+	if reply == "" {
+		h.sendDM(s, m.ChannelID, "返答を生成できませんでした。もう一度送ってみてください。")
+		return
+	}
+	h.dmSessions.append(m.ChannelID, content, reply, now)
+	h.sendDM(s, m.ChannelID, fitDiscordLength(reply))
+}
+
+func (h *InteractionHandler) sendDM(s *discordgo.Session, channelID, content string) {
+	if _, err := s.ChannelMessageSend(channelID, content); err != nil {
+		log.Printf("Error sending DM: %v", err)
+	}
+}
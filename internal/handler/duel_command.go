@@ -0,0 +1,252 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+
+	"github.com/chun37/doppelcord/internal/llm"
+)
+
+const (
+	// duelStopPrefix のカスタムIDは "duel_stop:<対戦ID>"
+	duelStopPrefix = "duel_stop:"
+
+	defaultDuelTurns = 6
+	maxDuelTurns     = 12
+	// duelTurnInterval はターンの間隔（読みやすさとレート制限のため）
+	duelTurnInterval = 2 * time.Second
+	// duelContextTurns はプロンプトに含める直前のターン数
+	duelContextTurns = 8
+)
+
+// duelParticipant は対戦の参加者
+type duelParticipant struct {
+	user *discordgo.User
+	name string
+}
+
+// duelRegistry は実行中の対戦を停止ボタンから止めるために保持する
+type duelRegistry struct {
+	mu    sync.Mutex
+	duels map[string]*runningDuel
+}
+
+type runningDuel struct {
+	cancel context.CancelFunc
+	// members は停止できるユーザー（実行者と参加者）
+	members map[string]bool
+}
+
+func newDuelRegistry() *duelRegistry {
+	return &duelRegistry{duels: make(map[string]*runningDuel)}
+}
+
+func (r *duelRegistry) add(id string, duel *runningDuel) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.duels[id] = duel
+}
+
+func (r *duelRegistry) remove(id string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.duels, id)
+}
+
+func (r *duelRegistry) get(id string) (*runningDuel, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	duel, ok := r.duels[id]
+	return duel, ok
+}
+
+func (h *InteractionHandler) handleDuel(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate) {
+	invoker := interactionUser(i)
+	turns := defaultDuelTurns
+	var topic string
+	slots := make(map[string]*discordgo.User)
+	for _, opt := range i.ApplicationCommandData().Options {
+		switch opt.Name {
+		case "a", "b", "c":
+			slots[opt.Name] = opt.UserValue(s)
+		case "turns":
+			turns = int(opt.IntValue())
+		case "topic":
+			topic = strings.TrimSpace(opt.StringValue())
+		}
+	}
+	// オプションの指定順によらず a → b → c の順に発言させる
+	var users []*discordgo.User
+	for _, name := range []string{"a", "b", "c"} {
+		if u, ok := slots[name]; ok {
+			users = append(users, u)
+		}
+	}
+	if turns < 2 || turns > maxDuelTurns {
+		h.respondEphemeral(s, i, fmt.Sprintf("turns は2〜%dで指定してください。", maxDuelTurns))
+		return
+	}
+
+	problem, err := h.checkParticipants(ctx, invoker.ID, users)
+	if err != nil {
+		log.Printf("Error checking duel participants: %v", err)
+		h.respondEphemeral(s, i, "参加者の確認に失敗しました。")
+		return
+	}
+	if problem != "" {
+		h.respondEphemeral(s, i, problem)
+		return
+	}
+	if !h.checkUsage(ctx, s, i) {
+		return
+	}
+
+	participants := make([]duelParticipant, 0, len(users))
+	mentions := make([]string, 0, len(users))
+	members := map[string]bool{invoker.ID: true}
+	for _, u := range users {
+		participants = append(participants, duelParticipant{user: u, name: h.memberName(s, i.GuildID, u)})
+		mentions = append(mentions, "<@"+u.ID+">")
+		members[u.ID] = true
+	}
+
+	header := fmt.Sprintf("⚔️ %s のなりきり対話（%dターン）", strings.Join(mentions, " vs "), turns)
+	if topic != "" {
+		header += fmt.Sprintf("\n話題: %s", topic)
+	}
+	err = s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Content:         header,
+			AllowedMentions: &discordgo.MessageAllowedMentions{},
+			Components: []discordgo.MessageComponent{
+				discordgo.ActionsRow{Components: []discordgo.MessageComponent{
+					discordgo.Button{
+						Label:    "停止",
+						Emoji:    &discordgo.ComponentEmoji{Name: "⏹️"},
+						Style:    discordgo.DangerButton,
+						CustomID: duelStopPrefix + i.ID,
+					},
+				}},
+			},
+		},
+	})
+	if err != nil {
+		log.Printf("Error responding to interaction: %v", err)
+		return
+	}
+
+	duelCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	h.duels.add(i.ID, &runningDuel{cancel: cancel, members: members})
+	defer h.duels.remove(i.ID)
+
+	result := h.runDuel(duelCtx, s, i, participants, turns, topic)
+	footer := header + "\n" + result
+	if _, err := s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+		Content:    &footer,
+		Components: &[]discordgo.MessageComponent{},
+	}); err != nil {
+		log.Printf("Error editing response: %v", err)
+	}
+}
+
+// runDuel は参加者の順にターンを生成して投稿し、終了理由を返す
+func (h *InteractionHandler) runDuel(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate, participants []duelParticipant, turns int, topic string) string {
+	var history []quotedMessage
+	for turn := 0; turn < turns; turn++ {
+		if turn > 0 {
+			select {
+			case <-ctx.Done():
+				return "⏹️ 停止されました。"
+			case <-time.After(duelTurnInterval):
+			}
+		}
+		if ctx.Err() != nil {
+			return "⏹️ 停止されました。"
+		}
+
+		speaker := participants[turn%len(participants)]
+		req := personaRequest{
+			userID:      speaker.user.ID,
+			guildID:     i.GuildID,
+			channelID:   i.ChannelID,
+			displayName: speaker.name,
+			topic:       topic,
+		}
+		if len(history) > 0 {
+			last := history[len(history)-1]
+			earlier := history[max(0, len(history)-1-duelContextTurns) : len(history)-1]
+			req.replyTo = &quotedMessage{author: last.author, content: last.content, chain: earlier}
+		}
+
+		chat, err := h.buildPersonaPrompt(ctx, s, req)
+		if err != nil {
+			log.Printf("Error building duel prompt: %v", err)
+			var pe *promptError
+			if errors.As(err, &pe) {
+				return fmt.Sprintf("⚠️ %s さんのターンで中断しました: %s", speaker.name, pe.message)
+			}
+			return "⚠️ 中断しました。"
+		}
+
+		completion, err := h.generate(ctx, newGeneration(i, "duel"), chat, llm.Params{})
+		if err != nil {
+			if ctx.Err() != nil {
+				return "⏹️ 停止されました。"
+			}
+			log.Printf("Error calling LLM API: %v", err)
+			return "⚠️ 生成に失敗したため中断しました。"
+		}
+
+		content := strings.TrimSpace(completion.Content)
+		history = append(history, quotedMessage{author: speaker.name, content: content})
+		_, err = s.ChannelMessageSendEmbed(i.ChannelID, &discordgo.MessageEmbed{
+			Author: &discordgo.MessageEmbedAuthor{
+				Name:    speaker.name + "（なりきり）",
+				IconURL: speaker.user.AvatarURL("64"),
+			},
+			Description: truncateRunes(content, maxEmbedDescription),
+			Footer:      &discordgo.MessageEmbedFooter{Text: fmt.Sprintf("%d / %d", turn+1, turns)},
+		})
+		if err != nil {
+			log.Printf("Error posting duel turn: %v", err)
+			return "⚠️ 投稿に失敗したため中断しました。"
+		}
+	}
+	return "✅ 終了しました。"
+}
+
+func (h *InteractionHandler) handleDuelStop(s *discordgo.Session, i *discordgo.InteractionCreate, customID string) {
+	duel, ok := h.duels.get(strings.TrimPrefix(customID, duelStopPrefix))
+	if !ok {
+		h.respondEphemeral(s, i, "この対話は既に終了しています。")
+		return
+	}
+	if !duel.members[interactionUser(i).ID] && !isGuildManager(i) {
+		h.respondEphemeral(s, i, "実行者と参加者のみ停止できます。")
+		return
+	}
+	duel.cancel()
+	h.respondEphemeral(s, i, "停止しました。")
+}
+
+// memberName はギルドでの表示名を返す。メンバー情報が取得できない場合はユーザーの表示名
+func (h *InteractionHandler) memberName(s *discordgo.Session, guildID string, u *discordgo.User) string {
+	if guildID != "" {
+		if m, err := s.State.Member(guildID, u.ID); err == nil {
+			return displayName(m, u)
+		}
+		if m, err := s.GuildMember(guildID, u.ID); err == nil {
+			return displayName(m, u)
+		}
+	}
+	return displayName(nil, u)
+}
@@ -0,0 +1,96 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+
+	"github.com/chun37/doppelcord/internal/domain"
+	"github.com/chun37/doppelcord/internal/prompt"
+)
+
+const (
+	// feedbackButtonPrefix のカスタムIDは "feedback:<生成ID>:<good|bad>"
+	feedbackButtonPrefix = "feedback:"
+
+	// maxFewShotExamples はプロンプトに含める高評価の生成例の最大数
+	maxFewShotExamples = 5
+)
+
+// feedbackButtons は生成結果に付ける👍/👎ボタンを返す
+func feedbackButtons(generationID int64) []discordgo.MessageComponent {
+	return []discordgo.MessageComponent{
+		discordgo.Button{
+			Emoji:    &discordgo.ComponentEmoji{Name: "👍"},
+			Style:    discordgo.SecondaryButton,
+			CustomID: fmt.Sprintf("%s%d:good", feedbackButtonPrefix, generationID),
+		},
+		discordgo.Button{
+			Emoji:    &discordgo.ComponentEmoji{Name: "👎"},
+			Style:    discordgo.SecondaryButton,
+			CustomID: fmt.Sprintf("%s%d:bad", feedbackButtonPrefix, generationID),
+		},
+	}
+}
+
+func (h *InteractionHandler) handleFeedback(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate, customID string) {
+	idText, ratingText, ok := strings.Cut(strings.TrimPrefix(customID, feedbackButtonPrefix), ":")
+	generationID, err := strconv.ParseInt(idText, 10, 64)
+	if !ok || err != nil {
+		log.Printf("Invalid feedback button ID: %s", customID)
+		return
+	}
+
+	// 評価は本人の生成例の選択に使うため、生成した本人のみ評価できる
+	gen, err := h.generationRepo.FindByID(ctx, generationID)
+	if err != nil {
+		log.Printf("Error fetching generation: %v", err)
+		h.respondEphemeral(s, i, "フィードバックの保存に失敗しました。")
+		return
+	}
+	if gen == nil || gen.DiscordID != interactionUser(i).ID {
+		h.respondEphemeral(s, i, "評価できるのは生成した本人のみです。")
+		return
+	}
+
+	rating := domain.FeedbackRatingGood
+	if ratingText == "bad" {
+		rating = domain.FeedbackRatingBad
+	}
+
+	err = h.generationRepo.SaveFeedback(ctx, &domain.GenerationFeedback{
+		GenerationID: generationID,
+		DiscordID:    interactionUser(i).ID,
+		Rating:       rating,
+	})
+	if err != nil {
+		log.Printf("Error saving feedback: %v", err)
+		h.respondEphemeral(s, i, "フィードバックの保存に失敗しました。")
+		return
+	}
+
+	if rating == domain.FeedbackRatingGood {
+		h.respondEphemeral(s, i, "👍 フィードバックを記録しました。今後の生成の参考にします。")
+	} else {
+		h.respondEphemeral(s, i, "👎 フィードバックを記録しました。")
+	}
+}
+
+// fewShotExamples はユーザーの高評価の生成をプロンプト用に整形して返す。取得に失敗した場合は空文字列
+func (h *InteractionHandler) fewShotExamples(ctx context.Context, userID string) string {
+	generations, err := h.generationRepo.FindWellRated(ctx, userID, maxFewShotExamples)
+	if err != nil {
+		log.Printf("Error fetching well-rated generations: %v", err)
+		return ""
+	}
+
+	examples := make([]string, 0, len(generations))
+	for _, gen := range generations {
+		examples = append(examples, gen.Content)
+	}
+	return prompt.FormatExamples(examples)
+}
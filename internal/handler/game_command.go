@@ -0,0 +1,489 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math/rand/v2"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+
+	"github.com/chun37/doppelcord/internal/llm"
+)
+
+const (
+	// gameVotePrefix のカスタムIDは "game_vote:<ラウンドID>:<選択肢>"
+	gameVotePrefix = "game_vote:"
+
+	gameModeRealOrFake = "real_or_fake"
+	gameModeWho        = "who"
+
+	defaultGameRounds  = 3
+	defaultGameSeconds = 30
+	// gameRoundInterval はラウンドの間隔
+	gameRoundInterval = 3 * time.Second
+	// gameMaxChoices は「誰の発言？」モードの選択肢の最大数
+	gameMaxChoices = 4
+	// gameMaxCandidates は出題対象として確認するユーザーの最大数
+	gameMaxCandidates = 20
+	// gameMaxMemberLookups はキャッシュにないメンバーをAPIで確認する最大人数
+	gameMaxMemberLookups = 10
+	// gameMinMessageLength は出題する本物のメッセージの最小文字数
+	gameMinMessageLength = 8
+	// gameLeaderboardSize はランキングに表示する人数
+	gameLeaderboardSize = 10
+)
+
+// gameCandidate は出題対象のユーザー
+type gameCandidate struct {
+	user *discordgo.User
+	name string
+}
+
+// gameRound は投票を受け付けているラウンド
+type gameRound struct {
+	mu    sync.Mutex
+	votes map[string]string
+	// labels は選択肢の値と表示名
+	labels map[string]string
+}
+
+func (r *gameRound) vote(userID, choice string) (string, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	label, ok := r.labels[choice]
+	if ok {
+		r.votes[userID] = choice
+	}
+	return label, ok
+}
+
+func (r *gameRound) snapshot() map[string]string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	votes := make(map[string]string, len(r.votes))
+	for userID, choice := range r.votes {
+		votes[userID] = choice
+	}
+	return votes
+}
+
+// gameRegistry は進行中のゲームとラウンドを保持する
+type gameRegistry struct {
+	mu       sync.Mutex
+	channels map[string]bool
+	rounds   map[string]*gameRound
+}
+
+func newGameRegistry() *gameRegistry {
+	return &gameRegistry{channels: make(map[string]bool), rounds: make(map[string]*gameRound)}
+}
+
+// startChannel はチャンネルでゲームを開始できればtrueを返す。1チャンネルで同時に1ゲームまで
+func (r *gameRegistry) startChannel(channelID string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.channels[channelID] {
+		return false
+	}
+	r.channels[channelID] = true
+	return true
+}
+
+func (r *gameRegistry) finishChannel(channelID string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.channels, channelID)
+}
+
+func (r *gameRegistry) openRound(id string, round *gameRound) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.rounds[id] = round
+}
+
+func (r *gameRegistry) closeRound(id string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.rounds, id)
+}
+
+func (r *gameRegistry) round(id string) (*gameRound, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	round, ok := r.rounds[id]
+	return round, ok
+}
+
+func (h *InteractionHandler) handleGame(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate) {
+	options := i.ApplicationCommandData().Options
+	if len(options) == 0 {
+		return
+	}
+	if i.GuildID == "" {
+		h.respondEphemeral(s, i, "このコマンドはサーバー内でのみ使用できます。")
+		return
+	}
+
+	switch sub := options[0]; sub.Name {
+	case "start":
+		h.handleGameStart(ctx, s, i, sub.Options)
+	case "leaderboard":
+		h.handleGameLeaderboard(ctx, s, i)
+	}
+}
+
+func (h *InteractionHandler) handleGameStart(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate, options []*discordgo.ApplicationCommandInteractionDataOption) {
+	mode, rounds, seconds := gameModeRealOrFake, defaultGameRounds, defaultGameSeconds
+	for _, opt := range options {
+		switch opt.Name {
+		case "mode":
+			mode = opt.StringValue()
+		case "rounds":
+			rounds = int(opt.IntValue())
+		case "seconds":
+			seconds = int(opt.IntValue())
+		}
+	}
+
+	if !h.games.startChannel(i.ChannelID) {
+		h.respondEphemeral(s, i, "このチャンネルでは既にゲームが進行中です。")
+		return
+	}
+	defer h.games.finishChannel(i.ChannelID)
+
+	candidates, err := h.gameCandidates(ctx, s, i.GuildID)
+	if err != nil {
+		log.Printf("Error listing game candidates: %v", err)
+		h.respondEphemeral(s, i, "出題対象のユーザーの取得に失敗しました。")
+		return
+	}
+	minCandidates := 1
+	if mode == gameModeWho {
+		minCandidates = 2
+	}
+	if len(candidates) < minCandidates {
+		h.respondEphemeral(s, i, fmt.Sprintf("出題できるユーザーが足りません（登録済みで `/consent allow:True` のメンバーが%d人以上必要です）。", minCandidates))
+		return
+	}
+	if !h.checkUsage(ctx, s, i) {
+		return
+	}
+
+	title := "本物？それともなりきり？"
+	if mode == gameModeWho {
+		title = "誰の発言？"
+	}
+	err = s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Content: fmt.Sprintf("🎮 **%s** を始めます！全%dラウンド・各%d秒で、ボタンで投票してください。", title, rounds, seconds),
+		},
+	})
+	if err != nil {
+		log.Printf("Error responding to interaction: %v", err)
+		return
+	}
+
+	// 本物のメッセージはこのサーバーの公開チャンネルから選ぶ。取得できなければなりきりだけで出題する
+	sources, err := publicChannelIDs(s, i.GuildID)
+	if err != nil {
+		log.Printf("Error listing guild channels: %v", err)
+	}
+
+	for n := 1; n <= rounds; n++ {
+		if n > 1 {
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(gameRoundInterval):
+			}
+		}
+		roundID := fmt.Sprintf("%s-%d", i.ID, n)
+		if err := h.playRound(ctx, s, i, roundID, mode, title, n, rounds, time.Duration(seconds)*time.Second, candidates, sources); err != nil {
+			log.Printf("Error playing game round: %v", err)
+			s.ChannelMessageSend(i.ChannelID, "⚠️ 出題に失敗したためゲームを終了します。")
+			return
+		}
+	}
+	s.ChannelMessageSend(i.ChannelID, "🏁 ゲーム終了！ `/game leaderboard` でランキングを確認できます。")
+}
+
+// playRound は1ラウンドを出題し、締め切り後に正解を発表して成績を記録する
+func (h *InteractionHandler) playRound(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate, roundID, mode, title string, n, total int, duration time.Duration, candidates []gameCandidate, sources []string) error {
+	author := candidates[rand.IntN(len(candidates))]
+	content, fake, err := h.roundContent(ctx, s, i, author, sources)
+	if err != nil {
+		return err
+	}
+
+	round := &gameRound{votes: make(map[string]string), labels: make(map[string]string)}
+	var answer string
+	var buttons []discordgo.MessageComponent
+	if mode == gameModeWho {
+		answer = author.user.ID
+		for _, c := range gameChoices(candidates, author) {
+			round.labels[c.user.ID] = c.name
+			buttons = append(buttons, gameButton(roundID, c.user.ID, c.name, ""))
+		}
+	} else {
+		answer = "real"
+		if fake {
+			answer = "fake"
+		}
+		round.labels["real"] = "本物"
+		round.labels["fake"] = "なりきり"
+		buttons = []discordgo.MessageComponent{
+			gameButton(roundID, "real", "本物", "🧑"),
+			gameButton(roundID, "fake", "なりきり", "🤖"),
+		}
+	}
+
+	deadline := time.Now().Add(duration)
+	embed := &discordgo.MessageEmbed{
+		Title:       fmt.Sprintf("ラウンド %d/%d: %s", n, total, title),
+		Description: fmt.Sprintf("%s\n\n締め切り: %s", truncateRunes(content, maxEmbedDescription-100), discordTimestamp(deadline, "R")),
+	}
+	msg, err := s.ChannelMessageSendComplex(i.ChannelID, &discordgo.MessageSend{
+		Embeds:     []*discordgo.MessageEmbed{embed},
+		Components: []discordgo.MessageComponent{discordgo.ActionsRow{Components: buttons}},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to post round: %w", err)
+	}
+
+	h.games.openRound(roundID, round)
+	select {
+	case <-ctx.Done():
+	case <-time.After(duration):
+	}
+	h.games.closeRound(roundID)
+
+	votes := round.snapshot()
+	correct := make(map[string]bool, len(votes))
+	var winners []string
+	for userID, choice := range votes {
+		correct[userID] = choice == answer
+		if choice == answer {
+			winners = append(winners, "<@"+userID+">")
+		}
+	}
+	if len(correct) > 0 {
+		if err := h.gameScoreRepo.RecordRound(ctx, i.GuildID, correct); err != nil {
+			log.Printf("Error recording game round: %v", err)
+		}
+	}
+
+	result := fmt.Sprintf("本物（%s さんの発言）", author.name)
+	if fake {
+		result = fmt.Sprintf("なりきり（%s さんのなりきり）", author.name)
+	}
+	winnerText := "なし"
+	if len(winners) > 0 {
+		winnerText = strings.Join(winners, " ")
+	}
+	embed.Description = truncateRunes(content, maxEmbedDescription)
+	embed.Fields = []*discordgo.MessageEmbedField{
+		{Name: "正解", Value: result},
+		{Name: fmt.Sprintf("正解者（%d人中%d人）", len(votes), len(winners)), Value: winnerText},
+	}
+	if _, err := s.ChannelMessageEditComplex(&discordgo.MessageEdit{
+		ID:         msg.ID,
+		Channel:    msg.ChannelID,
+		Embeds:     &[]*discordgo.MessageEmbed{embed},
+		Components: &[]discordgo.MessageComponent{},
+	}); err != nil {
+		log.Printf("Error revealing game round: %v", err)
+	}
+	return nil
+}
+
+// roundContent は出題するメッセージを返す。本物はsourcesのチャンネルの発言から選び、
+// 半分の確率で、または本物の候補がない場合はなりきりで生成する
+func (h *InteractionHandler) roundContent(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate, author gameCandidate, sources []string) (content string, fake bool, err error) {
+	if rand.IntN(2) == 0 {
+		messages, err := h.messageRepo.FindRandom(ctx, author.user.ID, sources, 10)
+		if err != nil {
+			return "", false, fmt.Errorf("failed to fetch random messages: %w", err)
+		}
+		for _, msg := range messages {
+			if len([]rune(msg.Content)) >= gameMinMessageLength && !strings.Contains(msg.Content, "http") {
+				return msg.Content, false, nil
+			}
+		}
+	}
+
+	chat, err := h.buildPersonaPrompt(ctx, s, personaRequest{
+		userID:      author.user.ID,
+		guildID:     i.GuildID,
+		channelID:   i.ChannelID,
+		displayName: author.name,
+	})
+	if err != nil {
+		var pe *promptError
+		if errors.As(err, &pe) {
+			return "", false, fmt.Errorf("failed to build prompt: %s", pe.Error())
+		}
+		return "", false, err
+	}
+	completion, err := h.generate(ctx, newGeneration(i, "game"), chat, llm.Params{})
+	if err != nil {
+		return "", false, fmt.Errorf("failed to generate: %w", err)
+	}
+	return strings.TrimSpace(completion.Content), true, nil
+}
+
+// gameCandidates はギルドのメンバーのうち、登録済みでなりきりの利用に同意しているユーザーを返す。
+// キャッシュにあるメンバーを優先し、APIでの取得はgameMaxMemberLookups人までにする
+func (h *InteractionHandler) gameCandidates(ctx context.Context, s *discordgo.Session, guildID string) ([]gameCandidate, error) {
+	ids, err := h.consentRepo.FindAllowed(ctx)
+	if err != nil {
+		return nil, err
+	}
+	rand.Shuffle(len(ids), func(a, b int) { ids[a], ids[b] = ids[b], ids[a] })
+
+	var candidates []gameCandidate
+	var uncached []string
+	for _, id := range ids {
+		if len(candidates) >= gameMaxCandidates {
+			return candidates, nil
+		}
+		member, err := s.State.Member(guildID, id)
+		if err != nil {
+			uncached = append(uncached, id)
+			continue
+		}
+		candidates = append(candidates, gameCandidate{user: member.User, name: displayName(member, member.User)})
+	}
+
+	for n, id := range uncached {
+		if len(candidates) >= gameMaxCandidates || n >= gameMaxMemberLookups {
+			break
+		}
+		member, err := s.GuildMember(guildID, id)
+		if err != nil {
+			continue
+		}
+		candidates = append(candidates, gameCandidate{user: member.User, name: displayName(member, member.User)})
+	}
+	return candidates, nil
+}
+
+// publicChannelIDs はギルドのチャンネルと公開スレッドのうち、@everyoneが閲覧できるもののIDを返す
+func publicChannelIDs(s *discordgo.Session, guildID string) ([]string, error) {
+	channels, err := s.GuildChannels(guildID)
+	if err != nil {
+		return nil, err
+	}
+	public := make(map[string]bool, len(channels))
+	ids := make([]string, 0, len(channels))
+	for _, ch := range channels {
+		if everyoneCanView(ch, guildID) {
+			public[ch.ID] = true
+			ids = append(ids, ch.ID)
+		}
+	}
+
+	// スレッドは取得できなければ含めない
+	threads, err := s.GuildThreadsActive(guildID)
+	if err != nil {
+		log.Printf("Error listing active threads: %v", err)
+		return ids, nil
+	}
+	for _, th := range threads.Threads {
+		if th.Type == discordgo.ChannelTypeGuildPublicThread && public[th.ParentID] {
+			ids = append(ids, th.ID)
+		}
+	}
+	return ids, nil
+}
+
+// everyoneCanView はチャンネルの権限の上書きで@everyone（ロールIDはギルドID）の閲覧が拒否されていなければtrueを返す
+func everyoneCanView(ch *discordgo.Channel, guildID string) bool {
+	for _, o := range ch.PermissionOverwrites {
+		if o.Type == discordgo.PermissionOverwriteTypeRole && o.ID == guildID {
+			return o.Deny&discordgo.PermissionViewChannel == 0
+		}
+	}
+	return true
+}
+
+// gameChoices は正解を含む最大gameMaxChoices人の選択肢を無作為な順で返す
+func gameChoices(candidates []gameCandidate, answer gameCandidate) []gameCandidate {
+	choices := []gameCandidate{answer}
+	for _, idx := range rand.Perm(len(candidates)) {
+		if len(choices) >= gameMaxChoices {
+			break
+		}
+		if c := candidates[idx]; c.user.ID != answer.user.ID {
+			choices = append(choices, c)
+		}
+	}
+	rand.Shuffle(len(choices), func(a, b int) { choices[a], choices[b] = choices[b], choices[a] })
+	return choices
+}
+
+func gameButton(roundID, choice, label, emoji string) discordgo.Button {
+	button := discordgo.Button{
+		Label:    truncateRunes(label, 80),
+		Style:    discordgo.PrimaryButton,
+		CustomID: gameVotePrefix + roundID + ":" + choice,
+	}
+	if emoji != "" {
+		button.Emoji = &discordgo.ComponentEmoji{Name: emoji}
+	}
+	return button
+}
+
+func (h *InteractionHandler) handleGameVote(s *discordgo.Session, i *discordgo.InteractionCreate, customID string) {
+	roundID, choice, ok := strings.Cut(strings.TrimPrefix(customID, gameVotePrefix), ":")
+	if !ok {
+		log.Printf("Invalid game vote button ID: %s", customID)
+		return
+	}
+
+	round, ok := h.games.round(roundID)
+	if !ok {
+		h.respondEphemeral(s, i, "このラウンドは締め切られました。")
+		return
+	}
+	label, ok := round.vote(interactionUser(i).ID, choice)
+	if !ok {
+		return
+	}
+	h.respondEphemeral(s, i, fmt.Sprintf("「%s」に投票しました（締め切りまでは変更できます）。", label))
+}
+
+func (h *InteractionHandler) handleGameLeaderboard(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate) {
+	scores, err := h.gameScoreRepo.Leaderboard(ctx, i.GuildID, gameLeaderboardSize)
+	if err != nil {
+		log.Printf("Error fetching leaderboard: %v", err)
+		h.respondEphemeral(s, i, "ランキングの取得に失敗しました。")
+		return
+	}
+	if len(scores) == 0 {
+		h.respondEphemeral(s, i, "まだ記録がありません。`/game start` で遊んでみましょう！")
+		return
+	}
+
+	var sb strings.Builder
+	for rank, score := range scores {
+		fmt.Fprintf(&sb, "%d. <@%s> **%d**pt（%dラウンド）\n", rank+1, score.DiscordID, score.Points, score.RoundsPlayed)
+	}
+	err = s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Embeds: []*discordgo.MessageEmbed{{
+				Title:       "🏆 Who said it? ランキング",
+				Description: sb.String(),
+			}},
+		},
+	})
+	if err != nil {
+		log.Printf("Error responding to interaction: %v", err)
+	}
+}
@@ -0,0 +1,102 @@
+package handler
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+
+	"github.com/chun37/doppelcord/internal/domain"
+	"github.com/chun37/doppelcord/internal/llm"
+	"github.com/chun37/doppelcord/internal/metrics"
+	"github.com/chun37/doppelcord/internal/usage"
+)
+
+// generate はLLMで生成し、レイテンシとトークン数をgenに設定して記録する。
+// genには呼び出し元でユーザー・チャンネル・コマンドを設定しておく
+func (h *InteractionHandler) generate(ctx context.Context, gen *domain.Generation, messages []llm.ChatMessage, params llm.Params) (*llm.Completion, error) {
+	start := time.Now()
+	completion, err := h.llmClient.Complete(ctx, messages, params)
+	gen.Latency = time.Since(start)
+	gen.Model = h.llmClient.Model()
+	gen.PromptHash = promptHash(messages)
+	gen.Status = domain.GenerationStatusSuccess
+	if err != nil {
+		gen.Status = domain.GenerationStatusError
+	} else {
+		gen.Model = completion.Model
+		gen.PromptTokens = completion.Usage.PromptTokens
+		gen.CompletionTokens = completion.Usage.CompletionTokens
+		gen.Content = completion.Content
+	}
+
+	if recordErr := h.usage.Record(ctx, gen); recordErr != nil {
+		log.Printf("Error recording generation: %v", recordErr)
+	}
+	return completion, err
+}
+
+// promptHash はメッセージ列のSHA-256を16進数で返す
+func promptHash(messages []llm.ChatMessage) string {
+	h := sha256.New()
+	for _, msg := range messages {
+		h.Write([]byte(msg.Role))
+		h.Write([]byte{0})
+		h.Write([]byte(msg.Content))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// newGeneration はインタラクションの実行者・チャンネルで生成の記録を作る
+func newGeneration(i *discordgo.InteractionCreate, command string) *domain.Generation {
+	return &domain.Generation{
+		DiscordID: interactionUser(i).ID,
+		GuildID:   i.GuildID,
+		ChannelID: i.ChannelID,
+		Command:   command,
+	}
+}
+
+// checkUsage は利用制限を確認し、制限に達している場合はその旨を応答してfalseを返す
+func (h *InteractionHandler) checkUsage(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate) bool {
+	err := h.usage.Check(ctx, interactionUser(i).ID)
+	if err == nil {
+		return true
+	}
+
+	var limitErr *usage.LimitError
+	if !errors.As(err, &limitErr) {
+		log.Printf("Error checking usage: %v", err)
+		h.respondEphemeral(s, i, "利用状況の確認に失敗しました。")
+		return false
+	}
+
+	metrics.QuotaRejections.Inc(string(limitErr.Reason))
+	h.respondEphemeral(s, i, limitMessage(limitErr))
+	return false
+}
+
+// limitMessage は利用制限に達したときの案内文を返す
+func limitMessage(err *usage.LimitError) string {
+	retry := discordTimestamp(err.RetryAt, "R")
+	switch err.Reason {
+	case usage.LimitReasonCooldown:
+		return fmt.Sprintf("少し時間を空けてください。%sにもう一度お試しください。", retry)
+	case usage.LimitReasonDaily:
+		return fmt.Sprintf("今日の利用上限に達しました。%sにリセットされます。使用量は /usage で確認できます。", retry)
+	case usage.LimitReasonMonthly:
+		return fmt.Sprintf("今月の利用上限に達しました。%sにリセットされます。使用量は /usage で確認できます。", retry)
+	}
+	return "利用上限に達しました。"
+}
+
+// discordTimestamp は閲覧者のタイムゾーンで表示されるDiscordのタイムスタンプ記法を返す
+func discordTimestamp(t time.Time, style string) string {
+	return fmt.Sprintf("<t:%d:%s>", t.Unix(), style)
+}
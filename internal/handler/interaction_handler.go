@@ -6,44 +6,171 @@ import (
 	"fmt"
 	"log"
 	"strings"
+	"time"
 
 	"github.com/bwmarrin/discordgo"
 
-	"github.com/chun37/doppelcord/internal/domain"
+	"github.com/chun37/doppelcord/internal/lifecycle"
 	"github.com/chun37/doppelcord/internal/llm"
+	"github.com/chun37/doppelcord/internal/metrics"
+	"github.com/chun37/doppelcord/internal/prompt"
 	"github.com/chun37/doppelcord/internal/repository"
 	"github.com/chun37/doppelcord/internal/repository/postgres"
+	"github.com/chun37/doppelcord/internal/usage"
 )
 
+// PromptConfig はプロンプト生成の設定
+type PromptConfig struct {
+	MaxMessages    int
+	MaxPromptChars int
+	// Location は時間帯の判定に使うタイムゾーン
+	Location *time.Location
+	// Strategy は発言履歴をLLMに渡す形式（空の場合はprompt.StrategyTemplate）
+	Strategy prompt.Strategy
+}
+
+// DMConfig はDMでのなりきりとの会話の設定
+type DMConfig struct {
+	// MaxTurns は会話の文脈として保持するやり取りの数
+	MaxTurns int
+	// SessionTTL は最後のやり取りから会話の文脈を保持する時間
+	SessionTTL time.Duration
+}
+
+// InteractionDeps はInteractionHandlerが使う依存関係
+type InteractionDeps struct {
+	UserRepo     repository.UserRepository
+	MessageRepo  repository.MessageRepository
+	TemplateRepo repository.PromptTemplateRepository
+	// GenerationRepo は生成履歴とフィードバックの保存先
+	GenerationRepo repository.GenerationRepository
+	StatsRepo      repository.StatsRepository
+	ConsentRepo    repository.ConsentRepository
+	GameScoreRepo  repository.GameScoreRepository
+	ScheduleRepo   repository.ScheduleRepository
+	AwayRepo       repository.AwayRepository
+	LLMClient      *llm.Client
+	Usage          *usage.Service
+	Tracker        *lifecycle.Tracker
+	Prompt         PromptConfig
+	DM             DMConfig
+}
+
 type InteractionHandler struct {
-	userRepo    repository.UserRepository
-	messageRepo repository.MessageRepository
-	llmClient   *llm.Client
+	userRepo       repository.UserRepository
+	messageRepo    repository.MessageRepository
+	templateRepo   repository.PromptTemplateRepository
+	generationRepo repository.GenerationRepository
+	statsRepo      repository.StatsRepository
+	consentRepo    repository.ConsentRepository
+	gameScoreRepo  repository.GameScoreRepository
+	scheduleRepo   repository.ScheduleRepository
+	awayRepo       repository.AwayRepository
+	resolver       *prompt.Resolver
+	llmClient      *llm.Client
+	usage          *usage.Service
+	tracker        *lifecycle.Tracker
+	promptConfig   PromptConfig
+	// prompts は再生成ボタン用に生成時のプロンプトを保持する
+	prompts *promptCache
+	// duels は実行中の/duel
+	duels *duelRegistry
+	// games は進行中の/game
+	games *gameRegistry
+	// awayLimiter は不在中の自動応答の間隔を制限する
+	awayLimiter *awayLimiter
+	// dmSessions はDMチャンネルごとの会話の文脈
+	dmSessions *dmSessions
 }
 
-func NewInteractionHandler(userRepo repository.UserRepository, messageRepo repository.MessageRepository, llmClient *llm.Client) *InteractionHandler {
+func NewInteractionHandler(deps InteractionDeps) *InteractionHandler {
 	return &InteractionHandler{
-		userRepo:    userRepo,
-		messageRepo: messageRepo,
-		llmClient:   llmClient,
+		userRepo:       deps.UserRepo,
+		messageRepo:    deps.MessageRepo,
+		templateRepo:   deps.TemplateRepo,
+		generationRepo: deps.GenerationRepo,
+		statsRepo:      deps.StatsRepo,
+		consentRepo:    deps.ConsentRepo,
+		gameScoreRepo:  deps.GameScoreRepo,
+		scheduleRepo:   deps.ScheduleRepo,
+		awayRepo:       deps.AwayRepo,
+		resolver:       prompt.NewResolver(deps.TemplateRepo),
+		llmClient:      deps.LLMClient,
+		usage:          deps.Usage,
+		tracker:        deps.Tracker,
+		promptConfig:   deps.Prompt,
+		prompts:        newPromptCache(),
+		duels:          newDuelRegistry(),
+		games:          newGameRegistry(),
+		awayLimiter:    newAwayLimiter(),
+		dmSessions:     newDMSessions(deps.DM),
 	}
 }
 
 func (h *InteractionHandler) Handle(s *discordgo.Session, i *discordgo.InteractionCreate) {
-	if i.Type != discordgo.InteractionApplicationCommand {
-		return
-	}
-
-	switch i.ApplicationCommandData().Name {
-	case "register":
-		h.handleRegister(s, i)
-	case "test":
-		h.handleTest(s, i)
+	switch i.Type {
+	case discordgo.InteractionApplicationCommand:
+		h.tracker.Run(func(ctx context.Context) {
+			name := i.ApplicationCommandData().Name
+			metrics.CommandInvocations.Inc(name)
+
+			switch name {
+			case "register":
+				h.handleRegister(ctx, s, i)
+			case "test":
+				h.handleTest(ctx, s, i)
+			case "template":
+				h.handleTemplate(ctx, s, i)
+			case "usage":
+				h.handleUsage(ctx, s, i)
+			case "search":
+				h.handleSearch(ctx, s, i)
+			case "stats":
+				h.handleStats(ctx, s, i)
+			case "consent":
+				h.handleConsent(ctx, s, i)
+			case "duel":
+				h.handleDuel(ctx, s, i)
+			case "game":
+				h.handleGame(ctx, s, i)
+			case "schedule":
+				h.handleSchedule(ctx, s, i)
+			case "away":
+				h.handleAway(ctx, s, i)
+			case "blend":
+				h.handleBlend(ctx, s, i)
+			case "channel-voice":
+				h.handleChannelVoice(ctx, s, i)
+			case "Reply as me":
+				h.handleReplyAsMe(ctx, s, i)
+			}
+		})
+	case discordgo.InteractionMessageComponent:
+		h.tracker.Run(func(ctx context.Context) {
+			customID := i.MessageComponentData().CustomID
+			switch {
+			case strings.HasPrefix(customID, feedbackButtonPrefix):
+				h.handleFeedback(ctx, s, i, customID)
+			case strings.HasPrefix(customID, rewriteButtonPrefix):
+				h.handleRewrite(ctx, s, i, customID)
+			case strings.HasPrefix(customID, duelStopPrefix):
+				h.handleDuelStop(s, i, customID)
+			case strings.HasPrefix(customID, gameVotePrefix):
+				h.handleGameVote(s, i, customID)
+			}
+		})
+	case discordgo.InteractionModalSubmit:
+		h.tracker.Run(func(ctx context.Context) {
+			customID := i.ModalSubmitData().CustomID
+			switch {
+			case strings.HasPrefix(customID, templateModalPrefix):
+				h.handleTemplateModal(ctx, s, i, customID)
+			}
+		})
 	}
 }
 
-func (h *InteractionHandler) handleRegister(s *discordgo.Session, i *discordgo.InteractionCreate) {
-	ctx := context.Background()
+func (h *InteractionHandler) handleRegister(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate) {
 	userID := i.Member.User.ID
 
 	isRegistered, err := h.userRepo.IsRegistered(ctx, userID)
@@ -98,104 +225,69 @@ func (h *InteractionHandler) respondWithError(s *discordgo.Session, i *discordgo
 	})
 }
 
-const (
-	maxDiscordLength = 2000
-	truncationSuffix = "\n...(切り詰められました)"
-	maxMessages      = 100
-	maxPromptChars   = 10000
-
-	systemPromptTemplate = `あなたは以下のメッセージ履歴を持つDiscordユーザーになりきってください。
-
-## このユーザーの発言履歴（新しい順）:
-%s
-
-## 指示:
-- 上記の発言履歴から、このユーザーの文体、口調、言葉遣い、絵文字の使い方、話題の傾向を分析してください
-- このユーザーとして自然にメッセージを送信してください
-- 履歴にある特徴的な表現や癖があれば再現してください
-- 不自然に履歴を引用したり、なりきりであることを示したりしないでください`
-
-	userPrompt = "何か一言メッセージを送ってください。"
-)
-
-func (h *InteractionHandler) handleTest(s *discordgo.Session, i *discordgo.InteractionCreate) {
-	// 遅延応答（LLM呼び出しは時間がかかるため）
-	err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
-		Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
+func (h *InteractionHandler) editResponse(s *discordgo.Session, i *discordgo.InteractionCreate, content string) {
+	_, err := s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+		Content: &content,
 	})
 	if err != nil {
-		log.Printf("Error deferring response: %v", err)
-		return
+		log.Printf("Error editing response: %v", err)
 	}
+}
 
-	ctx := context.Background()
-	userID := i.Member.User.ID
-	channelID := i.ChannelID
-
-	// 1. まずチャンネル指定で履歴取得
-	messages, err := h.messageRepo.FindByDiscordIDAndChannelID(ctx, userID, channelID, maxMessages)
+// editResponseWithComponents は応答の本文とボタンを更新する
+func (h *InteractionHandler) editResponseWithComponents(s *discordgo.Session, i *discordgo.InteractionCreate, content string, components []discordgo.MessageComponent) {
+	_, err := s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+		Content:    &content,
+		Components: &components,
+	})
 	if err != nil {
-		log.Printf("Error fetching messages by channel: %v", err)
-		h.editResponse(s, i, "メッセージ履歴の取得に失敗しました。")
-		return
-	}
-
-	// 2. チャンネルに履歴がなければ全チャンネルから取得
-	if len(messages) == 0 {
-		messages, err = h.messageRepo.FindByDiscordID(ctx, userID, maxMessages, nil)
-		if err != nil {
-			log.Printf("Error fetching messages: %v", err)
-			h.editResponse(s, i, "メッセージ履歴の取得に失敗しました。")
-			return
-		}
-	}
-
-	// 3. 履歴が全くない場合のエラー処理
-	if len(messages) == 0 {
-		h.editResponse(s, i, "あなたのメッセージ履歴がまだ保存されていません。先に /register で登録してからメッセージを送信してください。")
-		return
+		log.Printf("Error editing response: %v", err)
 	}
+}
 
-	// 4. systemプロンプトの生成
-	systemPrompt := h.buildSystemPrompt(messages)
-
-	// 5. LLM呼び出し
-	response, err := h.llmClient.ChatWithSystem(ctx, systemPrompt, userPrompt)
+func (h *InteractionHandler) respondEphemeral(s *discordgo.Session, i *discordgo.InteractionCreate, message string) {
+	err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Content: message,
+			Flags:   discordgo.MessageFlagsEphemeral,
+		},
+	})
 	if err != nil {
-		log.Printf("Error calling LLM API: %v", err)
-		h.editResponse(s, i, "LLM APIの呼び出しに失敗しました。")
-		return
+		log.Printf("Error responding to interaction: %v", err)
 	}
+}
 
-	// 6. 2000文字制限の処理
-	if len(response) > maxDiscordLength-len(truncationSuffix) {
-		response = response[:maxDiscordLength-len(truncationSuffix)] + truncationSuffix
+// interactionUser はインタラクションを実行したユーザーを返す（DMではMemberがnilになる）
+func interactionUser(i *discordgo.InteractionCreate) *discordgo.User {
+	if i.Member != nil {
+		return i.Member.User
 	}
-
-	h.editResponse(s, i, response)
+	return i.User
 }
 
-func (h *InteractionHandler) editResponse(s *discordgo.Session, i *discordgo.InteractionCreate, content string) {
-	_, err := s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
-		Content: &content,
-	})
-	if err != nil {
-		log.Printf("Error editing response: %v", err)
+// displayName はサーバーニックネーム > グローバル表示名 > ユーザー名の順で表示名を返す
+func displayName(member *discordgo.Member, user *discordgo.User) string {
+	if member != nil && member.Nick != "" {
+		return member.Nick
 	}
+	if user == nil {
+		return ""
+	}
+	if user.GlobalName != "" {
+		return user.GlobalName
+	}
+	return user.Username
 }
 
-func (h *InteractionHandler) buildSystemPrompt(messages []*domain.Message) string {
-	var sb strings.Builder
-	charCount := 0
-
-	for _, msg := range messages {
-		if charCount+len(msg.Content) > maxPromptChars {
-			break
-		}
-		sb.WriteString(msg.Content)
-		sb.WriteString("\n---\n")
-		charCount += len(msg.Content) + 5
+// channelName はチャンネル名を返す。取得できない場合は空文字列
+func channelName(s *discordgo.Session, channelID string) string {
+	if ch, err := s.State.Channel(channelID); err == nil {
+		return ch.Name
 	}
-
-	return fmt.Sprintf(systemPromptTemplate, sb.String())
+	ch, err := s.Channel(channelID)
+	if err != nil {
+		return ""
+	}
+	return ch.Name
 }
@@ -8,16 +8,49 @@ import (
 	"github.com/bwmarrin/discordgo"
 
 	"github.com/chun37/doppelcord/internal/domain"
+	"github.com/chun37/doppelcord/internal/lifecycle"
+	"github.com/chun37/doppelcord/internal/metrics"
 	"github.com/chun37/doppelcord/internal/repository"
 )
 
+// maxStoredReplyContent は返信先の本文として保存する最大文字数
+const maxStoredReplyContent = 500
+
+// MessageResponder はメッセージに応じてなりきりで返信する
+type MessageResponder interface {
+	// RespondAway は不在中のユーザーが言及されたメッセージに自動応答する
+	RespondAway(ctx context.Context, s *discordgo.Session, m *discordgo.MessageCreate)
+	// RespondDM は登録ユーザーからのDMに本人のなりきりとして返信する
+	RespondDM(ctx context.Context, s *discordgo.Session, m *discordgo.MessageCreate)
+}
+
+// MessageDeps はMessageHandlerの依存関係
+type MessageDeps struct {
+	UserRepo repository.UserRepository
+	MsgRepo  repository.MessageRepository
+	Tracker  *lifecycle.Tracker
+	// Responder は不在時の自動応答とDMでの会話を行う（nilの場合は返信しない）
+	Responder MessageResponder
+	// StoreDMs がtrueの場合はDMのメッセージも履歴として保存する
+	StoreDMs bool
+}
+
 type MessageHandler struct {
-	userRepo repository.UserRepository
-	msgRepo  repository.MessageRepository
+	userRepo  repository.UserRepository
+	msgRepo   repository.MessageRepository
+	tracker   *lifecycle.Tracker
+	responder MessageResponder
+	storeDMs  bool
 }
 
-func NewMessageHandler(userRepo repository.UserRepository, msgRepo repository.MessageRepository) *MessageHandler {
-	return &MessageHandler{userRepo: userRepo, msgRepo: msgRepo}
+func NewMessageHandler(deps MessageDeps) *MessageHandler {
+	return &MessageHandler{
+		userRepo:  deps.UserRepo,
+		msgRepo:   deps.MsgRepo,
+		tracker:   deps.Tracker,
+		responder: deps.Responder,
+		storeDMs:  deps.StoreDMs,
+	}
 }
 
 func (h *MessageHandler) Handle(s *discordgo.Session, m *discordgo.MessageCreate) {
@@ -25,7 +58,26 @@ func (h *MessageHandler) Handle(s *discordgo.Session, m *discordgo.MessageCreate
 		return
 	}
 
-	ctx := context.Background()
+	h.tracker.Run(func(ctx context.Context) {
+		// DMは既定では発言履歴に保存せず、なりきりとの会話として扱う
+		if m.GuildID == "" {
+			if h.storeDMs {
+				h.handle(ctx, s, m)
+			}
+			if h.responder != nil && !m.Author.Bot {
+				h.responder.RespondDM(ctx, s, m)
+			}
+			return
+		}
+
+		h.handle(ctx, s, m)
+		if h.responder != nil && len(m.Mentions) > 0 {
+			h.responder.RespondAway(ctx, s, m)
+		}
+	})
+}
+
+func (h *MessageHandler) handle(ctx context.Context, s *discordgo.Session, m *discordgo.MessageCreate) {
 	isRegistered, err := h.userRepo.IsRegistered(ctx, m.Author.ID)
 	if err != nil {
 		log.Printf("Error checking user registration: %v", err)
@@ -42,10 +94,30 @@ func (h *MessageHandler) Handle(s *discordgo.Session, m *discordgo.MessageCreate
 			Content:   m.Content,
 			CreatedAt: m.Timestamp,
 		}
+		setReplyContext(s, m.Message, msg)
 		if err := h.msgRepo.Save(ctx, msg); err != nil {
+			metrics.MessageSaveErrors.Inc()
 			log.Printf("Error saving message: %v", err)
+		} else {
+			metrics.MessagesIngested.Inc()
 		}
 	} else {
 		fmt.Printf("Channel ID: %s, Author ID: %s, Content: %s\n", m.ChannelID, m.Author.ID, m.Content)
 	}
 }
+
+// setReplyContext は返信先とスレッドの情報をmsgに設定する。返信先の本文はイベントに含まれている場合のみ保存する
+func setReplyContext(s *discordgo.Session, m *discordgo.Message, msg *domain.Message) {
+	if m.Type == discordgo.MessageTypeReply && m.MessageReference != nil {
+		msg.ReplyToMessageID = m.MessageReference.MessageID
+		if parent := m.ReferencedMessage; parent != nil {
+			if parent.Author != nil {
+				msg.ReplyToAuthorID = parent.Author.ID
+			}
+			msg.ReplyToContent = truncateRunes(parent.Content, maxStoredReplyContent)
+		}
+	}
+	if ch, err := s.State.Channel(m.ChannelID); err == nil && ch.IsThread() {
+		msg.ThreadParentID = ch.ParentID
+	}
+}
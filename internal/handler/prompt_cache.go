@@ -0,0 +1,78 @@
+package handler
+
+import (
+	"sync"
+	"time"
+
+	"github.com/chun37/doppelcord/internal/llm"
+)
+
+const (
+	// promptCacheTTL は再生成ボタンが使える期間
+	promptCacheTTL = 30 * time.Minute
+	// promptCacheMaxEntries はキャッシュする生成の最大数
+	promptCacheMaxEntries = 1000
+)
+
+// cachedPrompt は生成に使ったプロンプト。再生成・書き直しで同じ履歴を使うために保持する
+type cachedPrompt struct {
+	ownerID string
+	// messages は最初の生成のプロンプト。書き直しを重ねても伸ばさない
+	messages []llm.ChatMessage
+	params   llm.Params
+	// content は直近の生成結果
+	content   string
+	expiresAt time.Time
+}
+
+// promptCache は生成IDごとのプロンプトを期限付きで保持する
+type promptCache struct {
+	mu      sync.Mutex
+	entries map[int64]*cachedPrompt
+}
+
+func newPromptCache() *promptCache {
+	return &promptCache{entries: make(map[int64]*cachedPrompt)}
+}
+
+func (c *promptCache) put(generationID int64, entry *cachedPrompt) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	entry.expiresAt = now.Add(promptCacheTTL)
+	if len(c.entries) >= promptCacheMaxEntries {
+		c.evict(now)
+	}
+	c.entries[generationID] = entry
+}
+
+func (c *promptCache) get(generationID int64) (*cachedPrompt, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[generationID]
+	if !ok || time.Now().After(entry.expiresAt) {
+		delete(c.entries, generationID)
+		return nil, false
+	}
+	return entry, true
+}
+
+// evict は期限切れのエントリを削除し、それでも上限に達していれば最も古いエントリを削除する
+func (c *promptCache) evict(now time.Time) {
+	var oldestID int64
+	var oldest time.Time
+	for id, entry := range c.entries {
+		if now.After(entry.expiresAt) {
+			delete(c.entries, id)
+			continue
+		}
+		if oldest.IsZero() || entry.expiresAt.Before(oldest) {
+			oldestID, oldest = id, entry.expiresAt
+		}
+	}
+	if len(c.entries) >= promptCacheMaxEntries {
+		delete(c.entries, oldestID)
+	}
+}
@@ -0,0 +1,72 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"log"
+
+	"github.com/bwmarrin/discordgo"
+
+	"github.com/chun37/doppelcord/internal/llm"
+)
+
+// handleReplyAsMe はメッセージのコンテキストメニュー「Reply as me」で、
+// 対象のメッセージへの返信を実行者のなりきりで生成する
+func (h *InteractionHandler) handleReplyAsMe(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate) {
+	data := i.ApplicationCommandData()
+	var target *discordgo.Message
+	if data.Resolved != nil {
+		target = data.Resolved.Messages[data.TargetID]
+	}
+	if target == nil {
+		h.respondEphemeral(s, i, "対象のメッセージを取得できませんでした。")
+		return
+	}
+	if target.Content == "" {
+		h.respondEphemeral(s, i, "本文のないメッセージには返信できません。")
+		return
+	}
+
+	if !h.checkUsage(ctx, s, i) {
+		return
+	}
+
+	err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
+	})
+	if err != nil {
+		log.Printf("Error deferring response: %v", err)
+		return
+	}
+
+	// Resolvedのメッセージはチャンネルを含まないことがあるため補う
+	if target.ChannelID == "" {
+		target.ChannelID = i.ChannelID
+	}
+	req := interactionPersonaRequest(i)
+	req.replyTo = quoteWithChain(s, target)
+
+	chat, err := h.buildPersonaPrompt(ctx, s, req)
+	if err != nil {
+		log.Printf("Error building prompt: %v", err)
+		var pe *promptError
+		if errors.As(err, &pe) {
+			h.editResponse(s, i, pe.message)
+		}
+		return
+	}
+
+	gen := newGeneration(i, "reply_as_me")
+	completion, err := h.generate(ctx, gen, chat, llm.Params{})
+	if err != nil {
+		log.Printf("Error calling LLM API: %v", err)
+		h.editResponse(s, i, "LLM APIの呼び出しに失敗しました。")
+		return
+	}
+
+	h.respondGeneration(s, i, gen.ID, &cachedPrompt{
+		ownerID:  req.userID,
+		messages: chat,
+		content:  completion.Content,
+	})
+}
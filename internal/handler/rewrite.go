@@ -0,0 +1,130 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+
+	"github.com/chun37/doppelcord/internal/llm"
+)
+
+// rewriteButtonPrefix のカスタムIDは "rewrite:<生成ID>:<アクション>"
+const rewriteButtonPrefix = "rewrite:"
+
+// rewriteAction は生成結果に対する再生成・書き直しの種類
+type rewriteAction struct {
+	name  string
+	emoji string
+	label string
+	// instruction は直前の生成に続けて送る書き直しの指示。空の場合は同じプロンプトで再生成する
+	instruction string
+}
+
+var rewriteActions = []rewriteAction{
+	{name: "regenerate", emoji: "🔁", label: "再生成"},
+	{name: "shorter", emoji: "✂️", label: "短く", instruction: "今のメッセージを、同じ口調のままもっと短く書き直してください。メッセージ本文だけを出力してください。"},
+	{name: "casual", emoji: "🔥", label: "くだけて", instruction: "今のメッセージを、もっとくだけた口調で書き直してください。メッセージ本文だけを出力してください。"},
+	{name: "polite", emoji: "🎩", label: "丁寧に", instruction: "今のメッセージを、このユーザーらしさを残したまま丁寧な口調で書き直してください。メッセージ本文だけを出力してください。"},
+}
+
+func findRewriteAction(name string) (rewriteAction, bool) {
+	for _, action := range rewriteActions {
+		if action.name == name {
+			return action, true
+		}
+	}
+	return rewriteAction{}, false
+}
+
+// generationComponents は生成結果に付けるフィードバック・書き直しのボタンを返す
+func generationComponents(generationID int64) []discordgo.MessageComponent {
+	buttons := make([]discordgo.MessageComponent, 0, len(rewriteActions))
+	for _, action := range rewriteActions {
+		buttons = append(buttons, discordgo.Button{
+			Label:    action.label,
+			Emoji:    &discordgo.ComponentEmoji{Name: action.emoji},
+			Style:    discordgo.SecondaryButton,
+			CustomID: fmt.Sprintf("%s%d:%s", rewriteButtonPrefix, generationID, action.name),
+		})
+	}
+	return []discordgo.MessageComponent{
+		discordgo.ActionsRow{Components: feedbackButtons(generationID)},
+		discordgo.ActionsRow{Components: buttons},
+	}
+}
+
+// respondGeneration は生成結果でメッセージを更新し、再生成用にプロンプトをキャッシュする
+func (h *InteractionHandler) respondGeneration(s *discordgo.Session, i *discordgo.InteractionCreate, generationID int64, entry *cachedPrompt) {
+	content := fitDiscordLength(entry.content)
+	if generationID == 0 {
+		h.editResponseWithComponents(s, i, content, []discordgo.MessageComponent{})
+		return
+	}
+	h.prompts.put(generationID, entry)
+	h.editResponseWithComponents(s, i, content, generationComponents(generationID))
+}
+
+func (h *InteractionHandler) handleRewrite(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate, customID string) {
+	idText, actionName, ok := strings.Cut(strings.TrimPrefix(customID, rewriteButtonPrefix), ":")
+	generationID, err := strconv.ParseInt(idText, 10, 64)
+	action, found := findRewriteAction(actionName)
+	if !ok || err != nil || !found {
+		log.Printf("Invalid rewrite button ID: %s", customID)
+		return
+	}
+
+	cached, ok := h.prompts.get(generationID)
+	if !ok {
+		h.respondEphemeral(s, i, "時間が経ったため書き直せません。もう一度 /test を実行してください。")
+		return
+	}
+	if cached.ownerID != interactionUser(i).ID {
+		h.respondEphemeral(s, i, "生成を実行したユーザーのみ操作できます。")
+		return
+	}
+	if !h.checkUsage(ctx, s, i) {
+		return
+	}
+
+	// メッセージをその場で更新するため、更新の遅延応答を返す
+	err = s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseDeferredMessageUpdate,
+	})
+	if err != nil {
+		log.Printf("Error deferring response: %v", err)
+		return
+	}
+
+	// 書き直しは常に最初のプロンプトと直近の生成結果だけから組み立て、連続した書き直しでもリクエストを伸ばさない
+	messages := cached.messages
+	if action.instruction != "" {
+		messages = append(append([]llm.ChatMessage(nil), cached.messages...),
+			llm.ChatMessage{Role: "assistant", Content: cached.content},
+			llm.ChatMessage{Role: "user", Content: action.instruction},
+		)
+	}
+
+	gen := newGeneration(i, "rewrite:"+action.name)
+	completion, err := h.generate(ctx, gen, messages, cached.params)
+	if err != nil {
+		log.Printf("Error calling LLM API: %v", err)
+		if _, err := s.FollowupMessageCreate(i.Interaction, false, &discordgo.WebhookParams{
+			Content: "LLM APIの呼び出しに失敗しました。",
+			Flags:   discordgo.MessageFlagsEphemeral,
+		}); err != nil {
+			log.Printf("Error sending followup: %v", err)
+		}
+		return
+	}
+
+	h.respondGeneration(s, i, gen.ID, &cachedPrompt{
+		ownerID:  cached.ownerID,
+		messages: cached.messages,
+		params:   cached.params,
+		content:  completion.Content,
+	})
+}
@@ -0,0 +1,220 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+
+	"github.com/chun37/doppelcord/internal/domain"
+	"github.com/chun37/doppelcord/internal/schedule"
+)
+
+// maxSchedulesPerUser はユーザーごとのスケジュールの最大数
+const maxSchedulesPerUser = 5
+
+func (h *InteractionHandler) handleSchedule(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate) {
+	options := i.ApplicationCommandData().Options
+	if len(options) == 0 {
+		return
+	}
+
+	switch sub := options[0]; sub.Name {
+	case "cron":
+		h.handleScheduleAdd(ctx, s, i, domain.ScheduleKindCron, sub.Options)
+	case "random":
+		h.handleScheduleAdd(ctx, s, i, domain.ScheduleKindRandom, sub.Options)
+	case "list":
+		h.handleScheduleList(ctx, s, i)
+	case "remove":
+		h.handleScheduleRemove(ctx, s, i, sub.Options)
+	}
+}
+
+func (h *InteractionHandler) handleScheduleAdd(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate, kind domain.ScheduleKind, options []*discordgo.ApplicationCommandInteractionDataOption) {
+	if i.GuildID == "" {
+		h.respondEphemeral(s, i, "このコマンドはサーバー内でのみ使用できます。")
+		return
+	}
+
+	userID := interactionUser(i).ID
+	loc := h.promptConfig.Location
+	sched := &domain.Schedule{DiscordID: userID, GuildID: i.GuildID, Kind: kind}
+	var minMinutes, maxMinutes int
+	var until string
+	for _, opt := range options {
+		switch opt.Name {
+		case "channel":
+			sched.ChannelID = opt.ChannelValue(nil).ID
+		case "spec":
+			sched.CronSpec = strings.TrimSpace(opt.StringValue())
+		case "min_minutes":
+			minMinutes = int(opt.IntValue())
+		case "max_minutes":
+			maxMinutes = int(opt.IntValue())
+		case "topic":
+			sched.Topic = strings.TrimSpace(opt.StringValue())
+		case "until":
+			until = opt.StringValue()
+		}
+	}
+
+	registered, err := h.userRepo.IsRegistered(ctx, userID)
+	if err != nil {
+		log.Printf("Error checking registration: %v", err)
+		h.respondEphemeral(s, i, "登録状況の確認に失敗しました。")
+		return
+	}
+	if !registered {
+		h.respondEphemeral(s, i, "先に /register で登録してください。")
+		return
+	}
+
+	switch kind {
+	case domain.ScheduleKindCron:
+		if err := schedule.ValidateCron(sched.CronSpec, loc); err != nil {
+			h.respondEphemeral(s, i, fmt.Sprintf("実行時刻の指定が不正です（`分 時 日 月 曜日` の形式で、%s以上の間隔にしてください）: `%v`", schedule.MinInterval, err))
+			return
+		}
+	case domain.ScheduleKindRandom:
+		if maxMinutes == 0 {
+			maxMinutes = minMinutes
+		}
+		if maxMinutes < minMinutes {
+			h.respondEphemeral(s, i, "max_minutes は min_minutes 以上にしてください。")
+			return
+		}
+		sched.MinInterval = time.Duration(minMinutes) * time.Minute
+		sched.MaxInterval = time.Duration(maxMinutes) * time.Minute
+	}
+
+	now := time.Now()
+	if until != "" {
+		date, err := parseDate(until, loc)
+		if err != nil {
+			h.respondEphemeral(s, i, "until は YYYY-MM-DD の形式で指定してください。")
+			return
+		}
+		// 指定日の終わりまで実行する
+		endsAt := date.AddDate(0, 0, 1)
+		if !endsAt.After(now) {
+			h.respondEphemeral(s, i, "until には今日以降の日付を指定してください。")
+			return
+		}
+		sched.EndsAt = &endsAt
+	}
+
+	perms, err := s.UserChannelPermissions(userID, sched.ChannelID)
+	if err != nil {
+		log.Printf("Error fetching channel permissions: %v", err)
+		h.respondEphemeral(s, i, "チャンネルの権限を確認できませんでした。")
+		return
+	}
+	if perms&(discordgo.PermissionViewChannel|discordgo.PermissionSendMessages) != discordgo.PermissionViewChannel|discordgo.PermissionSendMessages {
+		h.respondEphemeral(s, i, "あなたが発言できないチャンネルには投稿できません。")
+		return
+	}
+
+	existing, err := h.scheduleRepo.ListByDiscordID(ctx, userID)
+	if err != nil {
+		log.Printf("Error listing schedules: %v", err)
+		h.respondEphemeral(s, i, "スケジュールの取得に失敗しました。")
+		return
+	}
+	if len(existing) >= maxSchedulesPerUser {
+		h.respondEphemeral(s, i, fmt.Sprintf("スケジュールは1人%d件までです。`/schedule remove` で不要なものを削除してください。", maxSchedulesPerUser))
+		return
+	}
+
+	next, err := schedule.Next(sched, now, loc)
+	if err != nil {
+		log.Printf("Error computing next schedule run: %v", err)
+		h.respondEphemeral(s, i, "次回の実行日時を計算できませんでした。")
+		return
+	}
+	if sched.EndsAt != nil && !next.Before(*sched.EndsAt) {
+		h.respondEphemeral(s, i, "終了日までに一度も実行されません。")
+		return
+	}
+	sched.NextRunAt = next
+
+	if err := h.scheduleRepo.Save(ctx, sched); err != nil {
+		log.Printf("Error saving schedule: %v", err)
+		h.respondEphemeral(s, i, "スケジュールの保存に失敗しました。")
+		return
+	}
+
+	fmt.Printf("スケジュールを作成しました: #%d (%s)\n", sched.ID, userID)
+	h.respondEphemeral(s, i, fmt.Sprintf("スケジュールを作成しました。\n%s", describeSchedule(sched)))
+}
+
+func (h *InteractionHandler) handleScheduleList(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate) {
+	schedules, err := h.scheduleRepo.ListByDiscordID(ctx, interactionUser(i).ID)
+	if err != nil {
+		log.Printf("Error listing schedules: %v", err)
+		h.respondEphemeral(s, i, "スケジュールの取得に失敗しました。")
+		return
+	}
+	if len(schedules) == 0 {
+		h.respondEphemeral(s, i, "スケジュールはありません。`/schedule cron` または `/schedule random` で作成できます。")
+		return
+	}
+
+	lines := make([]string, 0, len(schedules))
+	for _, sched := range schedules {
+		lines = append(lines, describeSchedule(sched))
+	}
+	h.respondEphemeral(s, i, strings.Join(lines, "\n"))
+}
+
+func (h *InteractionHandler) handleScheduleRemove(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate, options []*discordgo.ApplicationCommandInteractionDataOption) {
+	var id int64
+	for _, opt := range options {
+		if opt.Name == "id" {
+			id = opt.IntValue()
+		}
+	}
+
+	deleted, err := h.scheduleRepo.Delete(ctx, id, interactionUser(i).ID)
+	if err != nil {
+		log.Printf("Error deleting schedule: %v", err)
+		h.respondEphemeral(s, i, "スケジュールの削除に失敗しました。")
+		return
+	}
+	if !deleted {
+		h.respondEphemeral(s, i, fmt.Sprintf("スケジュール #%d は見つかりませんでした。", id))
+		return
+	}
+	h.respondEphemeral(s, i, fmt.Sprintf("スケジュール #%d を削除しました。", id))
+}
+
+// describeSchedule はスケジュールを一覧用の1行にする
+func describeSchedule(sched *domain.Schedule) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "- **#%d** <#%s> ", sched.ID, sched.ChannelID)
+	switch sched.Kind {
+	case domain.ScheduleKindCron:
+		fmt.Fprintf(&sb, "`%s`", sched.CronSpec)
+	case domain.ScheduleKindRandom:
+		fmt.Fprintf(&sb, "%s〜%sごと", formatMinutes(sched.MinInterval), formatMinutes(sched.MaxInterval))
+	}
+	fmt.Fprintf(&sb, " 次回 %s", discordTimestamp(sched.NextRunAt, "f"))
+	if sched.EndsAt != nil {
+		fmt.Fprintf(&sb, "（%sまで）", discordTimestamp(*sched.EndsAt, "d"))
+	}
+	if sched.Topic != "" {
+		fmt.Fprintf(&sb, " 話題: %s", sched.Topic)
+	}
+	return sb.String()
+}
+
+// formatMinutes は間隔を「90分」「2時間」のように表す
+func formatMinutes(d time.Duration) string {
+	if d%time.Hour == 0 {
+		return fmt.Sprintf("%d時間", int(d.Hours()))
+	}
+	return fmt.Sprintf("%d分", int(d.Minutes()))
+}
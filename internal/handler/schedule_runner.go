@@ -0,0 +1,236 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+
+	"github.com/chun37/doppelcord/internal/domain"
+	"github.com/chun37/doppelcord/internal/llm"
+	"github.com/chun37/doppelcord/internal/metrics"
+	"github.com/chun37/doppelcord/internal/schedule"
+	"github.com/chun37/doppelcord/internal/usage"
+)
+
+const (
+	schedulePollInterval = time.Minute
+	// scheduleBatchSize は1回の確認で取得する実行待ちのスケジュールの最大数
+	scheduleBatchSize = 100
+	// scheduleConcurrency は同時に実行するスケジュールの最大数
+	scheduleConcurrency = 4
+	// scheduleMisfireGrace より遅れた実行（停止中に過ぎた分など）は投稿せず次回に回す
+	scheduleMisfireGrace = 15 * time.Minute
+)
+
+// ShardSet はこのプロセスが担当するシャード
+type ShardSet interface {
+	// Count は全体のシャード数を返す
+	Count() int
+	// IDs はこのプロセスが担当するシャードIDを返す
+	IDs() []int
+	// SessionForGuild はギルドを担当するセッションを返す。担当外のギルドではnil
+	SessionForGuild(guildID string) *discordgo.Session
+}
+
+// RunSchedules は担当するシャードのギルドについて、実行日時を過ぎたスケジュールを定期的に確認して投稿する。
+// ctxがキャンセルされるまでブロックする
+func (h *InteractionHandler) RunSchedules(ctx context.Context, shards ShardSet) {
+	ticker := time.NewTicker(schedulePollInterval)
+	defer ticker.Stop()
+
+	for {
+		h.runDueSchedules(ctx, shards)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (h *InteractionHandler) runDueSchedules(ctx context.Context, shards ShardSet) {
+	due, err := h.scheduleRepo.FindDue(ctx, time.Now(), shards.Count(), shards.IDs(), scheduleBatchSize)
+	if err != nil {
+		if ctx.Err() == nil {
+			log.Printf("Error fetching due schedules: %v", err)
+		}
+		return
+	}
+
+	// 生成に時間のかかる回があっても後続の回が遅れすぎないよう、並行して実行する
+	sem := make(chan struct{}, scheduleConcurrency)
+	for _, sched := range due {
+		s := shards.SessionForGuild(sched.GuildID)
+		if s == nil {
+			continue
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case sem <- struct{}{}:
+		}
+		if !h.tracker.Go(func(ctx context.Context) {
+			defer func() { <-sem }()
+			h.runSchedule(ctx, s, sched.ID)
+		}) {
+			return
+		}
+	}
+}
+
+// runSchedule はスケジュールの実行を確保してから1回実行し、結果に応じて実行日時を更新する
+func (h *InteractionHandler) runSchedule(ctx context.Context, s *discordgo.Session, id int64) {
+	now := time.Now()
+	sched, next := h.claimSchedule(ctx, id, now)
+	if sched == nil {
+		return
+	}
+
+	if late := now.Sub(sched.NextRunAt); late > scheduleMisfireGrace {
+		fmt.Printf("Skipping schedule #%d: %s late\n", id, late.Round(time.Second))
+		return
+	}
+
+	var lastRunAt *time.Time
+	retryAt, err := h.postScheduled(ctx, s, sched)
+	switch {
+	case errors.Is(err, errNotGuildMember):
+		h.deleteSchedule(ctx, sched, "owner left the guild")
+		return
+	case err != nil:
+		log.Printf("Error running schedule #%d: %v", id, err)
+		return
+	case !retryAt.IsZero():
+		// クールダウン中は次の時刻まで待たず、明けたら投稿する
+		next = retryAt
+	default:
+		lastRunAt = &now
+	}
+
+	if err := h.scheduleRepo.UpdateRun(ctx, id, lastRunAt, next); err != nil {
+		log.Printf("Error updating schedule #%d: %v", id, err)
+	}
+}
+
+// claimSchedule はアドバイザリロックを取得し、実行日時を過ぎていれば次回の実行日時を先に進めて今回の実行を確保する。
+// ロックは確保の間だけ保持するため、複数のレプリカが同じ回を実行せず、生成中にDB接続を占有しない。
+// 実行しない場合はnilを返す。返すスケジュールのNextRunAtは今回の実行日時
+func (h *InteractionHandler) claimSchedule(ctx context.Context, id int64, now time.Time) (*domain.Schedule, time.Time) {
+	unlock, ok, err := h.scheduleRepo.TryLock(ctx, id)
+	if err != nil {
+		log.Printf("Error locking schedule %d: %v", id, err)
+		return nil, time.Time{}
+	}
+	if !ok {
+		return nil, time.Time{}
+	}
+	defer unlock()
+
+	// ロックを取得するまでに他のレプリカが確保済みの場合があるため、最新の状態で確認する
+	sched, err := h.scheduleRepo.FindByID(ctx, id)
+	if err != nil {
+		log.Printf("Error fetching schedule %d: %v", id, err)
+		return nil, time.Time{}
+	}
+	if sched == nil || sched.NextRunAt.After(now) {
+		return nil, time.Time{}
+	}
+
+	if sched.EndsAt != nil && !now.Before(*sched.EndsAt) {
+		h.deleteSchedule(ctx, sched, "ended")
+		return nil, time.Time{}
+	}
+
+	next, err := schedule.Next(sched, now, h.promptConfig.Location)
+	if err != nil {
+		log.Printf("Error computing next run of schedule %d: %v", id, err)
+		h.deleteSchedule(ctx, sched, "invalid")
+		return nil, time.Time{}
+	}
+	if err := h.scheduleRepo.UpdateRun(ctx, id, nil, next); err != nil {
+		log.Printf("Error claiming schedule #%d: %v", id, err)
+		return nil, time.Time{}
+	}
+	return sched, next
+}
+
+// errNotGuildMember はスケジュールの作成者がギルドのメンバーでないことを示す
+var errNotGuildMember = errors.New("schedule owner is not a guild member")
+
+// postScheduled は作成者のなりきりで生成して投稿する。
+// クールダウン中の場合は投稿せず、再試行する日時を返す。1日・1か月の上限に達している場合は投稿しない
+func (h *InteractionHandler) postScheduled(ctx context.Context, s *discordgo.Session, sched *domain.Schedule) (retryAt time.Time, err error) {
+	if err := h.usage.Check(ctx, sched.DiscordID); err != nil {
+		var limitErr *usage.LimitError
+		if !errors.As(err, &limitErr) {
+			return time.Time{}, fmt.Errorf("failed to check usage: %w", err)
+		}
+		metrics.QuotaRejections.Inc(string(limitErr.Reason))
+		if limitErr.Reason == usage.LimitReasonCooldown {
+			return limitErr.RetryAt, nil
+		}
+		fmt.Printf("Skipping schedule #%d: %s\n", sched.ID, limitErr)
+		return time.Time{}, nil
+	}
+
+	member, err := s.State.Member(sched.GuildID, sched.DiscordID)
+	if err != nil {
+		if member, err = s.GuildMember(sched.GuildID, sched.DiscordID); err != nil {
+			var restErr *discordgo.RESTError
+			if errors.As(err, &restErr) && restErr.Response != nil && restErr.Response.StatusCode == 404 {
+				return time.Time{}, errNotGuildMember
+			}
+			return time.Time{}, fmt.Errorf("failed to fetch member: %w", err)
+		}
+	}
+	name := displayName(member, member.User)
+
+	chat, err := h.buildPersonaPrompt(ctx, s, personaRequest{
+		userID:      sched.DiscordID,
+		guildID:     sched.GuildID,
+		channelID:   sched.ChannelID,
+		displayName: name,
+		topic:       sched.Topic,
+	})
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	gen := &domain.Generation{
+		DiscordID: sched.DiscordID,
+		GuildID:   sched.GuildID,
+		ChannelID: sched.ChannelID,
+		Command:   "schedule",
+	}
+	completion, err := h.generate(ctx, gen, chat, llm.Params{})
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to generate: %w", err)
+	}
+
+	_, err = s.ChannelMessageSendEmbed(sched.ChannelID, &discordgo.MessageEmbed{
+		Author: &discordgo.MessageEmbedAuthor{
+			Name:    name + "（なりきり）",
+			IconURL: member.User.AvatarURL("64"),
+		},
+		Description: truncateRunes(strings.TrimSpace(completion.Content), maxEmbedDescription),
+		Footer:      &discordgo.MessageEmbedFooter{Text: fmt.Sprintf("定期投稿 #%d", sched.ID)},
+	})
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to post: %w", err)
+	}
+	return time.Time{}, nil
+}
+
+func (h *InteractionHandler) deleteSchedule(ctx context.Context, sched *domain.Schedule, reason string) {
+	if _, err := h.scheduleRepo.Delete(ctx, sched.ID, sched.DiscordID); err != nil {
+		log.Printf("Error deleting schedule #%d: %v", sched.ID, err)
+		return
+	}
+	fmt.Printf("Deleted schedule #%d (%s)\n", sched.ID, reason)
+}
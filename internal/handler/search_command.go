@@ -0,0 +1,103 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+
+	"github.com/chun37/doppelcord/internal/repository"
+)
+
+const (
+	// searchResultLimit は/searchで表示する最大件数
+	searchResultLimit = 10
+	// searchSnippetLength は検索結果に表示する本文の最大文字数
+	searchSnippetLength = 80
+
+	dateLayout = "2006-01-02"
+)
+
+func (h *InteractionHandler) handleSearch(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate) {
+	// 自分のメッセージのみを検索できる
+	q := repository.MessageSearchQuery{
+		DiscordID: interactionUser(i).ID,
+		Limit:     searchResultLimit,
+	}
+	for _, opt := range i.ApplicationCommandData().Options {
+		switch opt.Name {
+		case "query":
+			q.Text = opt.StringValue()
+		case "channel":
+			q.ChannelID = opt.ChannelValue(s).ID
+		case "from":
+			from, err := parseDate(opt.StringValue(), h.promptConfig.Location)
+			if err != nil {
+				h.respondEphemeral(s, i, "from は YYYY-MM-DD 形式で指定してください。")
+				return
+			}
+			q.From = &from
+		case "to":
+			to, err := parseDate(opt.StringValue(), h.promptConfig.Location)
+			if err != nil {
+				h.respondEphemeral(s, i, "to は YYYY-MM-DD 形式で指定してください。")
+				return
+			}
+			// 指定日を含めるため翌日の0時より前を対象にする
+			to = to.AddDate(0, 0, 1)
+			q.To = &to
+		}
+	}
+	if strings.TrimSpace(q.Text) == "" {
+		h.respondEphemeral(s, i, "検索するキーワードを指定してください。")
+		return
+	}
+
+	messages, err := h.messageRepo.Search(ctx, q)
+	if err != nil {
+		log.Printf("Error searching messages: %v", err)
+		h.respondEphemeral(s, i, "メッセージの検索に失敗しました。")
+		return
+	}
+	if len(messages) == 0 {
+		h.respondEphemeral(s, i, "一致するメッセージは見つかりませんでした。")
+		return
+	}
+
+	guildID := i.GuildID
+	if guildID == "" {
+		guildID = "@me"
+	}
+	var sb strings.Builder
+	for _, msg := range messages {
+		snippet := strings.Join(strings.Fields(msg.Content), " ")
+		if len([]rune(snippet)) > searchSnippetLength {
+			snippet = truncateRunes(snippet, searchSnippetLength) + "…"
+		}
+		fmt.Fprintf(&sb, "%s <#%s> %s [→](https://discord.com/channels/%s/%s/%s)\n",
+			discordTimestamp(msg.CreatedAt, "d"), msg.ChannelID, snippet, guildID, msg.ChannelID, msg.MessageID)
+	}
+
+	err = s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Embeds: []*discordgo.MessageEmbed{{
+				Title:       fmt.Sprintf("「%s」の検索結果", truncateRunes(q.Text, 50)),
+				Description: sb.String(),
+				Footer:      &discordgo.MessageEmbedFooter{Text: fmt.Sprintf("新しい順に最大%d件", searchResultLimit)},
+			}},
+			Flags: discordgo.MessageFlagsEphemeral,
+		},
+	})
+	if err != nil {
+		log.Printf("Error responding to interaction: %v", err)
+	}
+}
+
+// parseDate はYYYY-MM-DD形式の日付をlocの0時として解析する
+func parseDate(value string, loc *time.Location) (time.Time, error) {
+	return time.ParseInLocation(dateLayout, strings.TrimSpace(value), loc)
+}
@@ -0,0 +1,159 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+
+	"github.com/chun37/doppelcord/internal/chart"
+	"github.com/chun37/doppelcord/internal/domain"
+)
+
+const (
+	// statsMonths は月別の件数を表示する月数
+	statsMonths = 6
+	// statsBarWidth はテキストの棒グラフの最大の長さ
+	statsBarWidth = 12
+
+	statsChartName = "activity.png"
+)
+
+func (h *InteractionHandler) handleStats(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate) {
+	withChart := false
+	for _, opt := range i.ApplicationCommandData().Options {
+		if opt.Name == "chart" {
+			withChart = opt.BoolValue()
+		}
+	}
+
+	// 集計に時間がかかることがあるため遅延応答する
+	err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{Flags: discordgo.MessageFlagsEphemeral},
+	})
+	if err != nil {
+		log.Printf("Error deferring response: %v", err)
+		return
+	}
+
+	loc := h.promptConfig.Location
+	now := time.Now().In(loc)
+	since := time.Date(now.Year(), now.Month()-statsMonths+1, 1, 0, 0, 0, 0, loc)
+
+	user := interactionUser(i)
+	stats, err := h.statsRepo.UserStats(ctx, user.ID, loc, since)
+	if err != nil {
+		log.Printf("Error aggregating stats: %v", err)
+		h.editResponse(s, i, "統計の集計に失敗しました。")
+		return
+	}
+	if stats.TotalMessages == 0 {
+		h.editResponse(s, i, "まだメッセージが保存されていません。/register で登録してからメッセージを送信してください。")
+		return
+	}
+
+	embed := statsEmbed(stats, displayName(i.Member, user), loc, since)
+	edit := &discordgo.WebhookEdit{Embeds: &[]*discordgo.MessageEmbed{embed}}
+	if withChart {
+		png, err := chart.DefaultBarChart.PNG(stats.Hours[:])
+		if err != nil {
+			log.Printf("Error rendering stats chart: %v", err)
+		} else {
+			embed.Image = &discordgo.MessageEmbedImage{URL: "attachment://" + statsChartName}
+			edit.Files = []*discordgo.File{{Name: statsChartName, ContentType: "image/png", Reader: bytes.NewReader(png)}}
+		}
+	}
+
+	if _, err := s.InteractionResponseEdit(i.Interaction, edit); err != nil {
+		log.Printf("Error editing response: %v", err)
+	}
+}
+
+// statsEmbed は統計を埋め込みに整形する
+func statsEmbed(stats *domain.UserStats, name string, loc *time.Location, since time.Time) *discordgo.MessageEmbed {
+	summary := fmt.Sprintf("メッセージ数: **%d**\n平均文字数: **%.1f**\n連続投稿: 現在 **%d日** / 最長 **%d日**",
+		stats.TotalMessages, stats.AverageLength, stats.CurrentStreak, stats.LongestStreak)
+	if stats.FirstMessageAt != nil {
+		summary += "\n最初の記録: " + discordTimestamp(*stats.FirstMessageAt, "D")
+	}
+
+	fields := []*discordgo.MessageEmbedField{
+		{Name: "概要", Value: summary},
+		{Name: "月別のメッセージ数", Value: monthlyBars(stats.Monthly, loc, since)},
+	}
+	if len(stats.Channels) > 0 {
+		var sb strings.Builder
+		for _, c := range stats.Channels {
+			fmt.Fprintf(&sb, "<#%s> %d\n", c.ChannelID, c.Count)
+		}
+		fields = append(fields, &discordgo.MessageEmbedField{Name: "よく発言するチャンネル", Value: sb.String(), Inline: true})
+	}
+	fields = append(fields, &discordgo.MessageEmbedField{Name: "よく発言する時間帯", Value: topHours(stats.Hours), Inline: true})
+	if len(stats.TopWords) > 0 {
+		fields = append(fields, &discordgo.MessageEmbedField{Name: "よく使う言葉", Value: joinTerms(stats.TopWords)})
+	}
+	if len(stats.TopEmoji) > 0 {
+		fields = append(fields, &discordgo.MessageEmbedField{Name: "よく使う絵文字", Value: joinTerms(stats.TopEmoji)})
+	}
+
+	return &discordgo.MessageEmbed{
+		Title:  fmt.Sprintf("%s さんの統計", name),
+		Fields: fields,
+		Footer: &discordgo.MessageEmbedFooter{Text: "時刻は " + loc.String() + " 基準・グラフは0〜23時の時間帯別の件数"},
+	}
+}
+
+// monthlyBars は月別の件数をテキストの棒グラフにする。件数のない月も表示する
+func monthlyBars(monthly []domain.PeriodCount, loc *time.Location, since time.Time) string {
+	counts := make(map[string]int64, len(monthly))
+	var maxCount int64
+	for _, pc := range monthly {
+		counts[pc.Period.Format("2006-01")] = pc.Count
+		maxCount = max(maxCount, pc.Count)
+	}
+
+	var sb strings.Builder
+	for m := 0; m < statsMonths; m++ {
+		month := since.AddDate(0, m, 0).In(loc)
+		count := counts[month.Format("2006-01")]
+		width := 0
+		if maxCount > 0 {
+			width = int(count * statsBarWidth / maxCount)
+		}
+		fmt.Fprintf(&sb, "`%s` %s %d\n", month.Format("2006-01"), strings.Repeat("▇", width), count)
+	}
+	return sb.String()
+}
+
+// topHours は件数の多い時間帯を3つ返す
+func topHours(hours [24]int64) string {
+	var sb strings.Builder
+	used := [24]bool{}
+	for rank := 0; rank < 3; rank++ {
+		best := -1
+		for h, count := range hours {
+			if !used[h] && count > 0 && (best < 0 || count > hours[best]) {
+				best = h
+			}
+		}
+		if best < 0 {
+			break
+		}
+		used[best] = true
+		fmt.Fprintf(&sb, "%d時台 %d\n", best, hours[best])
+	}
+	return sb.String()
+}
+
+func joinTerms(terms []domain.TermCount) string {
+	parts := make([]string, 0, len(terms))
+	for _, t := range terms {
+		parts = append(parts, fmt.Sprintf("%s (%d)", t.Term, t.Count))
+	}
+	return strings.Join(parts, "、")
+}
@@ -0,0 +1,313 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+
+	"github.com/chun37/doppelcord/internal/domain"
+	"github.com/chun37/doppelcord/internal/prompt"
+)
+
+const (
+	templateModalPrefix = "template_set:"
+	templateHistoryMax  = 10
+
+	templateSystemInputID = "system"
+	templateUserInputID   = "user"
+
+	// Discordのモーダルのテキスト入力は最大4000文字
+	maxTemplateInputLength = 4000
+)
+
+func (h *InteractionHandler) handleTemplate(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate) {
+	if !isGuildManager(i) {
+		h.respondEphemeral(s, i, "このコマンドはサーバーの管理権限を持つユーザーのみ使用できます。")
+		return
+	}
+
+	options := i.ApplicationCommandData().Options
+	if len(options) == 0 {
+		return
+	}
+	sub := options[0]
+
+	switch sub.Name {
+	case "preview":
+		h.handleTemplatePreview(ctx, s, i, sub.Options)
+	case "set":
+		h.handleTemplateSet(ctx, s, i, sub.Options)
+	case "rollback":
+		h.handleTemplateRollback(ctx, s, i, sub.Options)
+	case "history":
+		h.handleTemplateHistory(ctx, s, i, sub.Options)
+	}
+}
+
+// isGuildManager はサーバー管理権限を持つメンバーか判定する
+func isGuildManager(i *discordgo.InteractionCreate) bool {
+	if i.Member == nil {
+		return false
+	}
+	return i.Member.Permissions&(discordgo.PermissionManageGuild|discordgo.PermissionAdministrator) != 0
+}
+
+// templateScope はオプションから対象のスコープとIDを決める。userオプションがあればユーザー単位、なければギルド単位
+func templateScope(s *discordgo.Session, i *discordgo.InteractionCreate, options []*discordgo.ApplicationCommandInteractionDataOption) (domain.PromptTemplateScope, string) {
+	for _, opt := range options {
+		if opt.Name == "user" {
+			return domain.PromptTemplateScopeUser, opt.UserValue(s).ID
+		}
+	}
+	return domain.PromptTemplateScopeGuild, i.GuildID
+}
+
+func scopeLabel(scope domain.PromptTemplateScope, scopeID string) string {
+	if scope == domain.PromptTemplateScopeUser {
+		return fmt.Sprintf("<@%s> のテンプレート", scopeID)
+	}
+	return "サーバーのテンプレート"
+}
+
+func (h *InteractionHandler) handleTemplatePreview(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate, options []*discordgo.ApplicationCommandInteractionDataOption) {
+	guildID, userID := i.GuildID, ""
+	if scope, scopeID := templateScope(s, i, options); scope == domain.PromptTemplateScopeUser {
+		userID = scopeID
+	}
+
+	resolved, err := h.resolver.Resolve(ctx, guildID, userID)
+	if err != nil {
+		log.Printf("Error resolving prompt template: %v", err)
+		h.respondEphemeral(s, i, "テンプレートの取得に失敗しました。")
+		return
+	}
+
+	// 他のユーザーの履歴を見せないよう、プレビューには常に実行者の履歴を使う
+	messages, err := h.messageRepo.FindByDiscordID(ctx, interactionUser(i).ID, h.promptConfig.MaxMessages, nil)
+	if err != nil {
+		log.Printf("Error fetching messages: %v", err)
+		h.respondEphemeral(s, i, "メッセージ履歴の取得に失敗しました。")
+		return
+	}
+
+	data := h.promptData(s, i.ChannelID, displayName(i.Member, interactionUser(i)), messages)
+	data.Examples = h.fewShotExamples(ctx, interactionUser(i).ID)
+	turns := h.applyHistory(&data, messages, h.replyHistory(ctx, interactionUser(i).ID, nil), h.promptConfig.MaxPromptChars)
+	systemPrompt, userPrompt, err := resolved.Template.Render(data)
+	if err != nil {
+		h.respondEphemeral(s, i, fmt.Sprintf("テンプレートの展開に失敗しました（%s）:\n```\n%v\n```", resolved.Source, err))
+		return
+	}
+
+	var preview strings.Builder
+	for _, m := range prompt.ComposeChat(systemPrompt, turns, userPrompt) {
+		fmt.Fprintf(&preview, "# %s\n%s\n\n", m.Role, m.Content)
+	}
+	err = s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Content: fmt.Sprintf("適用されるテンプレート: `%s`（履歴は実行者のものを使用）", resolved.Source),
+			Flags:   discordgo.MessageFlagsEphemeral,
+			Files: []*discordgo.File{{
+				Name:        "prompt.txt",
+				ContentType: "text/plain",
+				Reader:      strings.NewReader(preview.String()),
+			}},
+		},
+	})
+	if err != nil {
+		log.Printf("Error responding to interaction: %v", err)
+	}
+}
+
+func (h *InteractionHandler) handleTemplateSet(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate, options []*discordgo.ApplicationCommandInteractionDataOption) {
+	scope, scopeID := templateScope(s, i, options)
+
+	// 現在のテンプレート（なければデフォルト）を初期値として表示する
+	current := prompt.DefaultTemplate
+	latest, err := h.templateRepo.FindLatest(ctx, scope, scopeID)
+	if err != nil {
+		log.Printf("Error fetching prompt template: %v", err)
+		h.respondEphemeral(s, i, "テンプレートの取得に失敗しました。")
+		return
+	}
+	if latest != nil {
+		current = prompt.Template{System: latest.SystemTemplate, User: latest.UserTemplate}
+	}
+
+	err = s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseModal,
+		Data: &discordgo.InteractionResponseData{
+			CustomID: fmt.Sprintf("%s%s:%s", templateModalPrefix, scope, scopeID),
+			Title:    "プロンプトテンプレートの設定",
+			Components: []discordgo.MessageComponent{
+				discordgo.ActionsRow{Components: []discordgo.MessageComponent{
+					discordgo.TextInput{
+						CustomID:  templateSystemInputID,
+						Label:     "systemプロンプト",
+						Style:     discordgo.TextInputParagraph,
+						Value:     truncateRunes(current.System, maxTemplateInputLength),
+						Required:  true,
+						MaxLength: maxTemplateInputLength,
+					},
+				}},
+				discordgo.ActionsRow{Components: []discordgo.MessageComponent{
+					discordgo.TextInput{
+						CustomID:  templateUserInputID,
+						Label:     "userプロンプト",
+						Style:     discordgo.TextInputParagraph,
+						Value:     truncateRunes(current.User, maxTemplateInputLength),
+						Required:  true,
+						MaxLength: maxTemplateInputLength,
+					},
+				}},
+			},
+		},
+	})
+	if err != nil {
+		log.Printf("Error opening template modal: %v", err)
+	}
+}
+
+func (h *InteractionHandler) handleTemplateModal(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate, customID string) {
+	if !isGuildManager(i) {
+		h.respondEphemeral(s, i, "このコマンドはサーバーの管理権限を持つユーザーのみ使用できます。")
+		return
+	}
+
+	scope, scopeID, ok := strings.Cut(strings.TrimPrefix(customID, templateModalPrefix), ":")
+	if !ok {
+		return
+	}
+	// ギルド単位のテンプレートは操作中のギルドのものに限る
+	if domain.PromptTemplateScope(scope) == domain.PromptTemplateScopeGuild && scopeID != i.GuildID {
+		return
+	}
+
+	values := modalValues(i.ModalSubmitData())
+	tmpl := prompt.Template{System: values[templateSystemInputID], User: values[templateUserInputID]}
+	if err := tmpl.Validate(); err != nil {
+		h.respondEphemeral(s, i, fmt.Sprintf("テンプレートが不正です:\n```\n%v\n```", err))
+		return
+	}
+
+	saved := &domain.PromptTemplate{
+		Scope:          domain.PromptTemplateScope(scope),
+		ScopeID:        scopeID,
+		SystemTemplate: tmpl.System,
+		UserTemplate:   tmpl.User,
+		CreatedBy:      interactionUser(i).ID,
+	}
+	if err := h.templateRepo.Save(ctx, saved); err != nil {
+		log.Printf("Error saving prompt template: %v", err)
+		h.respondEphemeral(s, i, "テンプレートの保存に失敗しました。")
+		return
+	}
+
+	fmt.Printf("プロンプトテンプレートを保存しました: %s:%s v%d\n", saved.Scope, saved.ScopeID, saved.Version)
+	h.respondEphemeral(s, i, fmt.Sprintf("%sを v%d として保存しました。", scopeLabel(saved.Scope, saved.ScopeID), saved.Version))
+}
+
+func (h *InteractionHandler) handleTemplateRollback(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate, options []*discordgo.ApplicationCommandInteractionDataOption) {
+	scope, scopeID := templateScope(s, i, options)
+
+	latest, err := h.templateRepo.FindLatest(ctx, scope, scopeID)
+	if err != nil {
+		log.Printf("Error fetching prompt template: %v", err)
+		h.respondEphemeral(s, i, "テンプレートの取得に失敗しました。")
+		return
+	}
+	if latest == nil {
+		h.respondEphemeral(s, i, scopeLabel(scope, scopeID)+"は設定されていません。")
+		return
+	}
+
+	version := latest.Version - 1
+	for _, opt := range options {
+		if opt.Name == "version" {
+			version = int(opt.IntValue())
+		}
+	}
+	if version < 1 {
+		h.respondEphemeral(s, i, "戻せる以前のバージョンがありません。")
+		return
+	}
+
+	target, err := h.templateRepo.FindVersion(ctx, scope, scopeID, version)
+	if err != nil {
+		log.Printf("Error fetching prompt template: %v", err)
+		h.respondEphemeral(s, i, "テンプレートの取得に失敗しました。")
+		return
+	}
+	if target == nil {
+		h.respondEphemeral(s, i, fmt.Sprintf("v%d は存在しません。", version))
+		return
+	}
+
+	// 履歴を残すため、対象バージョンの内容を新しいバージョンとして保存する
+	restored := &domain.PromptTemplate{
+		Scope:          scope,
+		ScopeID:        scopeID,
+		SystemTemplate: target.SystemTemplate,
+		UserTemplate:   target.UserTemplate,
+		CreatedBy:      interactionUser(i).ID,
+	}
+	if err := h.templateRepo.Save(ctx, restored); err != nil {
+		log.Printf("Error saving prompt template: %v", err)
+		h.respondEphemeral(s, i, "テンプレートの保存に失敗しました。")
+		return
+	}
+
+	h.respondEphemeral(s, i, fmt.Sprintf("%sを v%d の内容に戻しました（v%d として保存）。", scopeLabel(scope, scopeID), target.Version, restored.Version))
+}
+
+func (h *InteractionHandler) handleTemplateHistory(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate, options []*discordgo.ApplicationCommandInteractionDataOption) {
+	scope, scopeID := templateScope(s, i, options)
+
+	versions, err := h.templateRepo.ListVersions(ctx, scope, scopeID, templateHistoryMax)
+	if err != nil {
+		log.Printf("Error listing prompt templates: %v", err)
+		h.respondEphemeral(s, i, "テンプレートの取得に失敗しました。")
+		return
+	}
+	if len(versions) == 0 {
+		h.respondEphemeral(s, i, scopeLabel(scope, scopeID)+"は設定されていません（デフォルトを使用中）。")
+		return
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%sの履歴（新しい順）:\n", scopeLabel(scope, scopeID))
+	for _, v := range versions {
+		fmt.Fprintf(&sb, "- v%d: <@%s> <t:%d:f>\n", v.Version, v.CreatedBy, v.CreatedAt.Unix())
+	}
+	h.respondEphemeral(s, i, sb.String())
+}
+
+// modalValues はモーダルのテキスト入力をCustomIDごとの値にまとめる
+func modalValues(data discordgo.ModalSubmitInteractionData) map[string]string {
+	values := make(map[string]string)
+	for _, row := range data.Components {
+		actions, ok := row.(*discordgo.ActionsRow)
+		if !ok {
+			continue
+		}
+		for _, c := range actions.Components {
+			if input, ok := c.(*discordgo.TextInput); ok {
+				values[input.CustomID] = input.Value
+			}
+		}
+	}
+	return values
+}
+
+// truncateRunes は文字列をmaxRunes文字以内に切り詰める
+func truncateRunes(s string, maxRunes int) string {
+	runes := []rune(s)
+	if len(runes) <= maxRunes {
+		return s
+	}
+	return string(runes[:maxRunes])
+}
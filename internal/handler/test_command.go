@@ -0,0 +1,442 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+
+	"github.com/chun37/doppelcord/internal/domain"
+	"github.com/chun37/doppelcord/internal/llm"
+	"github.com/chun37/doppelcord/internal/prompt"
+	"github.com/chun37/doppelcord/internal/repository"
+)
+
+const (
+	maxDiscordLength = 2000
+	// maxEmbedDescription は埋め込みの説明文の最大文字数
+	maxEmbedDescription = 4096
+	truncationSuffix    = "\n...(切り詰められました)"
+
+	// maxRelatedMessages は話題に関連する発言として含める最大件数
+	maxRelatedMessages = 20
+	// maxReplyExamples は他の人への返信の例として含める最大件数
+	maxReplyExamples = 10
+	// maxReplyChain は返信先からたどる返信の最大件数
+	maxReplyChain = 5
+)
+
+// lengthPreset は/testのlengthオプションごとの生成設定
+type lengthPreset struct {
+	maxTokens   int
+	instruction string
+}
+
+var lengthPresets = map[string]lengthPreset{
+	"short":  {maxTokens: 60, instruction: "一文程度の短いメッセージにしてください。"},
+	"normal": {maxTokens: 200, instruction: ""},
+	"long":   {maxTokens: 600, instruction: "いつもより長めに、数文のメッセージにしてください。"},
+}
+
+// quotedMessage は返信先として引用するメッセージ
+type quotedMessage struct {
+	author  string
+	content string
+	// chain は返信先が返信していたメッセージの連鎖（古い順）
+	chain []quotedMessage
+}
+
+// historyPeriod はなりきりに使う履歴の期間。Fromがゼロ値の場合は下限なし
+type historyPeriod struct {
+	from  time.Time
+	to    time.Time
+	label string
+}
+
+// timeRange はリポジトリでの絞り込みに使う範囲を返す。期間の指定がなければ絞り込まない
+func (p *historyPeriod) timeRange() repository.TimeRange {
+	if p == nil {
+		return repository.TimeRange{}
+	}
+	return repository.TimeRange{From: p.from, To: p.to}
+}
+
+// periodOptions は/testの期間指定のオプション（YYYY-MM-DD）
+type periodOptions struct {
+	asOf string
+	from string
+	to   string
+}
+
+// parsePeriod は期間指定のオプションを解析する。指定がなければnil。
+// 日付はlocのタイムゾーンで解釈し、as_of・toはその日の終わりまでを含める
+func parsePeriod(opts periodOptions, loc *time.Location) (*historyPeriod, error) {
+	if opts.asOf == "" && opts.from == "" && opts.to == "" {
+		return nil, nil
+	}
+	if opts.asOf != "" && (opts.from != "" || opts.to != "") {
+		return nil, errors.New("as_of と from/to は同時に指定できません。")
+	}
+
+	parse := func(name, value string) (time.Time, error) {
+		t, err := parseDate(value, loc)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("%s は YYYY-MM-DD の形式で指定してください。", name)
+		}
+		return t, nil
+	}
+
+	if opts.asOf != "" {
+		asOf, err := parse("as_of", opts.asOf)
+		if err != nil {
+			return nil, err
+		}
+		return &historyPeriod{to: asOf.AddDate(0, 0, 1), label: asOf.Format(dateLayout) + "時点"}, nil
+	}
+
+	period := &historyPeriod{to: time.Now().In(loc)}
+	fromLabel, toLabel := "", ""
+	if opts.from != "" {
+		from, err := parse("from", opts.from)
+		if err != nil {
+			return nil, err
+		}
+		period.from, fromLabel = from, from.Format(dateLayout)
+	}
+	if opts.to != "" {
+		to, err := parse("to", opts.to)
+		if err != nil {
+			return nil, err
+		}
+		period.to, toLabel = to.AddDate(0, 0, 1), to.Format(dateLayout)
+	}
+	if !period.from.Before(period.to) {
+		return nil, errors.New("from には to より前の日付を指定してください。")
+	}
+	period.label = fromLabel + "〜" + toLabel
+	return period, nil
+}
+
+// personaRequest はなりきり生成の依頼内容
+type personaRequest struct {
+	userID    string
+	guildID   string
+	channelID string
+	// displayName はなりきる対象の表示名
+	displayName string
+	topic       string
+	replyTo     *quotedMessage
+	// instruction はユーザープロンプトの末尾に追加する指示
+	instruction string
+	// period が指定されていれば、その期間の履歴だけでなりきる
+	period *historyPeriod
+}
+
+// promptError はプロンプトの組み立てに失敗した理由。messageは実行者にそのまま表示する
+type promptError struct {
+	message string
+	err     error
+}
+
+func (e *promptError) Error() string {
+	if e.err == nil {
+		return e.message
+	}
+	return e.message + ": " + e.err.Error()
+}
+
+func (e *promptError) Unwrap() error {
+	return e.err
+}
+
+// interactionPersonaRequest は実行者自身のなりきりの依頼を作る
+func interactionPersonaRequest(i *discordgo.InteractionCreate) personaRequest {
+	return personaRequest{
+		userID:      interactionUser(i).ID,
+		guildID:     i.GuildID,
+		channelID:   i.ChannelID,
+		displayName: displayName(i.Member, interactionUser(i)),
+	}
+}
+
+// testOptions は/testのオプションを生成パラメータと依頼内容に反映し、返信先の参照と期間指定を返す
+func testOptions(i *discordgo.InteractionCreate, req *personaRequest) (params llm.Params, replyRef string, period periodOptions) {
+	for _, opt := range i.ApplicationCommandData().Options {
+		switch opt.Name {
+		case "temperature":
+			params.Temperature = llm.Float64(opt.FloatValue())
+		case "length":
+			if preset, ok := lengthPresets[opt.StringValue()]; ok {
+				params.MaxTokens = llm.Int(preset.maxTokens)
+				req.instruction = preset.instruction
+			}
+		case "topic":
+			req.topic = strings.TrimSpace(opt.StringValue())
+		case "reply_to":
+			replyRef = strings.TrimSpace(opt.StringValue())
+		case "as_of":
+			period.asOf = opt.StringValue()
+		case "from":
+			period.from = opt.StringValue()
+		case "to":
+			period.to = opt.StringValue()
+		}
+	}
+	return params, replyRef, period
+}
+
+func (h *InteractionHandler) handleTest(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate) {
+	if !h.checkUsage(ctx, s, i) {
+		return
+	}
+
+	// 遅延応答（LLM呼び出しは時間がかかるため）
+	err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
+	})
+	if err != nil {
+		log.Printf("Error deferring response: %v", err)
+		return
+	}
+
+	req := interactionPersonaRequest(i)
+	params, replyRef, periodOpts := testOptions(i, &req)
+
+	period, err := parsePeriod(periodOpts, h.promptConfig.Location)
+	if err != nil {
+		h.editResponse(s, i, err.Error())
+		return
+	}
+	req.period = period
+
+	// 1. 返信先が指定されていれば引用するメッセージを取得
+	if replyRef != "" {
+		channelID, messageID, ok := parseMessageRef(replyRef, i.ChannelID)
+		if !ok {
+			h.editResponse(s, i, "reply_to にはメッセージのリンクかIDを指定してください。")
+			return
+		}
+		target, err := s.ChannelMessage(channelID, messageID)
+		if err != nil {
+			log.Printf("Error fetching reply target: %v", err)
+			h.editResponse(s, i, "返信先のメッセージを取得できませんでした。")
+			return
+		}
+		req.replyTo = quoteWithChain(s, target)
+	}
+
+	// 2. 履歴とテンプレートからプロンプトを生成
+	chat, err := h.buildPersonaPrompt(ctx, s, req)
+	if err != nil {
+		log.Printf("Error building prompt: %v", err)
+		var pe *promptError
+		if errors.As(err, &pe) {
+			h.editResponse(s, i, pe.message)
+		}
+		return
+	}
+
+	// 3. オプションで生成パラメータを上書きしてLLM呼び出し
+	gen := newGeneration(i, "test")
+	completion, err := h.generate(ctx, gen, chat, params)
+	if err != nil {
+		log.Printf("Error calling LLM API: %v", err)
+		h.editResponse(s, i, "LLM APIの呼び出しに失敗しました。")
+		return
+	}
+
+	// 4. 2000文字制限に収めて返信し、記録できた生成にはフィードバック・書き直しのボタンを付ける
+	h.respondGeneration(s, i, gen.ID, &cachedPrompt{
+		ownerID:  req.userID,
+		messages: chat,
+		params:   params,
+		content:  completion.Content,
+	})
+}
+
+// buildPersonaPrompt は依頼者の履歴・テンプレートからsystemとuserのメッセージを組み立てる。
+// 失敗した場合は*promptErrorを返す
+func (h *InteractionHandler) buildPersonaPrompt(ctx context.Context, s *discordgo.Session, req personaRequest) ([]llm.ChatMessage, error) {
+	messages, err := h.personaHistory(ctx, req.userID, req.channelID, req.period)
+	if err != nil {
+		return nil, &promptError{message: "メッセージ履歴の取得に失敗しました。", err: err}
+	}
+	if len(messages) == 0 {
+		if req.period != nil {
+			return nil, &promptError{message: fmt.Sprintf("%sのメッセージ履歴がありません。", req.period.label)}
+		}
+		return nil, &promptError{message: "あなたのメッセージ履歴がまだ保存されていません。先に /register で登録してからメッセージを送信してください。"}
+	}
+
+	resolved, err := h.resolver.Resolve(ctx, req.guildID, req.userID)
+	if err != nil {
+		return nil, &promptError{message: "プロンプトテンプレートの取得に失敗しました。", err: err}
+	}
+
+	data := h.promptData(s, req.channelID, req.displayName, messages)
+	if req.period != nil {
+		// 高評価の生成例は現在の本人らしさなので、過去の期間のなりきりには含めない
+		data.Period = req.period.label
+	} else {
+		data.Examples = h.fewShotExamples(ctx, req.userID)
+	}
+	data.Topic = req.topic
+	if req.replyTo != nil {
+		data.ReplyTo = req.replyTo.content
+		data.ReplyAuthor = req.replyTo.author
+		data.Conversation = formatConversation(req.replyTo.chain)
+	}
+
+	// 話題（なければ返信先の本文）に関連する発言を履歴とは別に含める
+	historyBudget := h.promptConfig.MaxPromptChars
+	query := req.topic
+	if query == "" && req.replyTo != nil {
+		query = req.replyTo.content
+	}
+	if query != "" {
+		relatedBudget := h.promptConfig.MaxPromptChars / 4
+		data.Related = prompt.FormatHistory(h.relatedHistory(ctx, req.userID, query, req.period), relatedBudget)
+		historyBudget -= len(data.Related)
+	}
+	turns := h.applyHistory(&data, messages, h.replyHistory(ctx, req.userID, req.period), historyBudget)
+
+	systemPrompt, userPrompt, err := resolved.Template.Render(data)
+	if err != nil {
+		return nil, &promptError{message: "プロンプトテンプレートの展開に失敗しました。", err: err}
+	}
+	if req.instruction != "" {
+		userPrompt += "\n" + req.instruction
+	}
+
+	return prompt.ComposeChat(systemPrompt, turns, userPrompt), nil
+}
+
+// applyHistory は発言履歴と他の人への返信をPROMPT_STRATEGYに応じた形式でプロンプトに含める。
+// StrategyChatの場合は会話形式の履歴を返し、それ以外はテンプレート変数に設定してnilを返す
+func (h *InteractionHandler) applyHistory(data *prompt.Data, messages, replies []*domain.Message, budget int) []llm.ChatMessage {
+	return prompt.ApplyHistory(h.promptConfig.Strategy, data, messages, replies, budget, h.promptConfig.MaxPromptChars/4)
+}
+
+// personaHistory はユーザーの履歴を新しい順に返す。まずチャンネル内の履歴を使い、なければ全チャンネルから取得する。
+// 期間が指定されている場合は、チャンネルを問わずその期間の履歴を返す
+func (h *InteractionHandler) personaHistory(ctx context.Context, userID, channelID string, period *historyPeriod) ([]*domain.Message, error) {
+	if period != nil {
+		if period.from.IsZero() {
+			return h.messageRepo.FindByDiscordID(ctx, userID, h.promptConfig.MaxMessages, &period.to)
+		}
+		return h.messageRepo.FindByDiscordIDInRange(ctx, userID, period.from, period.to, h.promptConfig.MaxMessages)
+	}
+
+	messages, err := h.messageRepo.FindByDiscordIDAndChannelID(ctx, userID, channelID, h.promptConfig.MaxMessages)
+	if err != nil || len(messages) > 0 {
+		return messages, err
+	}
+	return h.messageRepo.FindByDiscordID(ctx, userID, h.promptConfig.MaxMessages, nil)
+}
+
+// relatedHistory はqueryに関連するユーザーの発言を返す。期間が指定されていれば期間内の発言に限る。
+// 検索に失敗した場合はnil
+func (h *InteractionHandler) relatedHistory(ctx context.Context, userID, query string, period *historyPeriod) []*domain.Message {
+	related, err := h.messageRepo.FindRelated(ctx, userID, query, period.timeRange(), maxRelatedMessages)
+	if err != nil {
+		log.Printf("Error searching related messages: %v", err)
+		return nil
+	}
+	return related
+}
+
+// replyHistory はユーザーが他のユーザーに返信した発言を新しい順に返す。期間が指定されていれば期間内の発言に限る。
+// 取得に失敗した場合はnil
+func (h *InteractionHandler) replyHistory(ctx context.Context, userID string, period *historyPeriod) []*domain.Message {
+	replies, err := h.messageRepo.FindReplies(ctx, userID, period.timeRange(), maxReplyExamples)
+	if err != nil {
+		log.Printf("Error fetching replies: %v", err)
+		return nil
+	}
+	return replies
+}
+
+// parseMessageRef はメッセージリンク（https://discord.com/channels/<guild>/<channel>/<message>）
+// またはメッセージIDから、チャンネルIDとメッセージIDを取り出す。IDのみの場合はdefaultChannelIDのメッセージとみなす
+func parseMessageRef(ref, defaultChannelID string) (channelID, messageID string, ok bool) {
+	if _, path, found := strings.Cut(ref, "/channels/"); found {
+		parts := strings.Split(strings.Trim(path, "/"), "/")
+		if len(parts) != 3 || !isSnowflake(parts[1]) || !isSnowflake(parts[2]) {
+			return "", "", false
+		}
+		return parts[1], parts[2], true
+	}
+	if !isSnowflake(ref) {
+		return "", "", false
+	}
+	return defaultChannelID, ref, true
+}
+
+func isSnowflake(id string) bool {
+	if id == "" {
+		return false
+	}
+	for _, r := range id {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// quoteWithChain はメッセージを引用し、返信元をたどって最大maxReplyChain件の連鎖を付ける
+func quoteWithChain(s *discordgo.Session, msg *discordgo.Message) *quotedMessage {
+	quote := &quotedMessage{author: displayName(msg.Member, msg.Author), content: msg.Content}
+
+	current := msg
+	for len(quote.chain) < maxReplyChain && current.MessageReference != nil {
+		parent := current.ReferencedMessage
+		if parent == nil {
+			ref := current.MessageReference
+			channelID := ref.ChannelID
+			if channelID == "" {
+				channelID = current.ChannelID
+			}
+			var err error
+			if parent, err = s.ChannelMessage(channelID, ref.MessageID); err != nil {
+				log.Printf("Error fetching referenced message: %v", err)
+				break
+			}
+		}
+		quote.chain = append([]quotedMessage{{author: displayName(parent.Member, parent.Author), content: parent.Content}}, quote.chain...)
+		current = parent
+	}
+	return quote
+}
+
+// formatConversation は返信の連鎖を "名前: 本文" の行にする
+func formatConversation(chain []quotedMessage) string {
+	lines := make([]string, 0, len(chain))
+	for _, m := range chain {
+		lines = append(lines, fmt.Sprintf("%s: %s", m.author, m.content))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// fitDiscordLength はDiscordの文字数制限に収まるよう文字単位で切り詰める
+func fitDiscordLength(content string) string {
+	limit := maxDiscordLength - len([]rune(truncationSuffix))
+	if len([]rune(content)) <= maxDiscordLength {
+		return content
+	}
+	return truncateRunes(content, limit) + truncationSuffix
+}
+
+// promptData は履歴と投稿先のチャンネル・表示名からテンプレート変数を組み立てる
+func (h *InteractionHandler) promptData(s *discordgo.Session, channelID, name string, messages []*domain.Message) prompt.Data {
+	return prompt.Data{
+		History:     prompt.FormatHistory(messages, h.promptConfig.MaxPromptChars),
+		ChannelName: channelName(s, channelID),
+		DisplayName: name,
+		TimeOfDay:   prompt.TimeOfDay(time.Now().In(h.promptConfig.Location)),
+	}
+}
@@ -0,0 +1,56 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/bwmarrin/discordgo"
+
+	"github.com/chun37/doppelcord/internal/domain"
+)
+
+func (h *InteractionHandler) handleUsage(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate) {
+	report, err := h.usage.Report(ctx, interactionUser(i).ID)
+	if err != nil {
+		log.Printf("Error fetching usage: %v", err)
+		h.respondEphemeral(s, i, "使用量の取得に失敗しました。")
+		return
+	}
+
+	fields := []*discordgo.MessageEmbedField{
+		{Name: "今日", Value: usageValue(report.Today, report.Limits.DailyTokens), Inline: true},
+		{Name: "今月", Value: usageValue(report.ThisMonth, report.Limits.MonthlyTokens), Inline: true},
+	}
+	if report.Limits.Cooldown > 0 {
+		value := fmt.Sprintf("%s（待ち時間なし）", report.Limits.Cooldown)
+		if !report.CooldownUntil.IsZero() {
+			value = fmt.Sprintf("%s（%sに解除）", report.Limits.Cooldown, discordTimestamp(report.CooldownUntil, "R"))
+		}
+		fields = append(fields, &discordgo.MessageEmbedField{Name: "クールダウン", Value: value})
+	}
+
+	err = s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Embeds: []*discordgo.MessageEmbed{{
+				Title:  "LLMの使用量",
+				Fields: fields,
+			}},
+			Flags: discordgo.MessageFlagsEphemeral,
+		},
+	})
+	if err != nil {
+		log.Printf("Error responding to interaction: %v", err)
+	}
+}
+
+// usageValue は使用量と上限を表示用の文字列にする
+func usageValue(summary domain.UsageSummary, limit int64) string {
+	limitText := "無制限"
+	if limit > 0 {
+		limitText = fmt.Sprintf("%d", limit)
+	}
+	return fmt.Sprintf("%d / %s トークン\n生成 %d 回（入力 %d・出力 %d）",
+		summary.TotalTokens(), limitText, summary.Generations, summary.PromptTokens, summary.CompletionTokens)
+}
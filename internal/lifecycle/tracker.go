@@ -0,0 +1,87 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrDrainTimeout はドレインがタイムアウト内に完了しなかったことを示す
+var ErrDrainTimeout = errors.New("drain timed out")
+
+// Tracker は実行中のハンドラー処理を追跡し、シャットダウン時にドレインする
+type Tracker struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+	mu     sync.Mutex
+	closed bool
+}
+
+// NewTracker は新しいTrackerを生成する。
+// 処理に渡すコンテキストは親のキャンセルを引き継がず、ドレインのタイムアウト時にのみキャンセルされる
+func NewTracker(parent context.Context) *Tracker {
+	ctx, cancel := context.WithCancel(context.WithoutCancel(parent))
+	return &Tracker{ctx: ctx, cancel: cancel}
+}
+
+// Run はfnを追跡対象として同期的に実行する。ドレイン開始後はfnを実行せずfalseを返す
+func (t *Tracker) Run(fn func(ctx context.Context)) bool {
+	if !t.add() {
+		return false
+	}
+	defer t.wg.Done()
+
+	fn(t.ctx)
+	return true
+}
+
+// Go はfnを追跡対象として別goroutineで実行する。ドレイン開始後はfnを実行せずfalseを返す
+func (t *Tracker) Go(fn func(ctx context.Context)) bool {
+	if !t.add() {
+		return false
+	}
+	go func() {
+		defer t.wg.Done()
+		fn(t.ctx)
+	}()
+	return true
+}
+
+func (t *Tracker) add() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.closed {
+		return false
+	}
+	t.wg.Add(1)
+	return true
+}
+
+// Drain は新規処理の受付を止め、実行中の処理の完了をtimeoutまで待つ。
+// タイムアウトした場合は処理のコンテキストをキャンセルし、ErrDrainTimeoutを返す
+func (t *Tracker) Drain(timeout time.Duration) error {
+	t.mu.Lock()
+	t.closed = true
+	t.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		t.wg.Wait()
+		close(done)
+	}()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case <-done:
+		t.cancel()
+		return nil
+	case <-timer.C:
+		t.cancel()
+		return ErrDrainTimeout
+	}
+}
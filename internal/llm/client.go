@@ -10,13 +10,27 @@ import (
 	"log"
 	"net/http"
 	"time"
+
+	"github.com/chun37/doppelcord/internal/metrics"
 )
 
 // Config はLLMクライアントの設定
 type Config struct {
-	APIURL string
-	APIKey string
-	Model  string
+	APIURL  string
+	APIKey  string
+	Model   string
+	Timeout time.Duration
+	// Defaults はリクエストごとに指定されなかった生成パラメータのデフォルト値
+	Defaults Params
+}
+
+// Completion は生成結果
+type Completion struct {
+	Content      string
+	FinishReason string
+	// Model はAPIが報告したモデル名（報告がなければリクエストしたモデル名）
+	Model string
+	Usage Usage
 }
 
 // Client はOpenAI互換APIクライアント
@@ -30,91 +44,98 @@ func NewClient(config Config) *Client {
 	return &Client{
 		config: config,
 		httpClient: &http.Client{
-			Timeout: 30 * time.Second,
+			Timeout: config.Timeout,
 		},
 	}
 }
 
+// Model はリクエストに使うモデル名を返す
+func (c *Client) Model() string {
+	return c.config.Model
+}
+
 // Chat はチャットリクエストを送信し、レスポンスを返す
 func (c *Client) Chat(ctx context.Context, prompt string) (string, error) {
+	return c.content(ctx, []ChatMessage{
+		{Role: "user", Content: prompt},
+	})
+}
+
+// ChatWithSystem はsystemプロンプト付きでチャットリクエストを送信
+func (c *Client) ChatWithSystem(ctx context.Context, systemPrompt, userPrompt string) (string, error) {
+	return c.content(ctx, []ChatMessage{
+		{Role: "system", Content: systemPrompt},
+		{Role: "user", Content: userPrompt},
+	})
+}
+
+// Complete は任意のメッセージ列と生成パラメータでリクエストを送信する。
+// paramsで指定されなかった項目には設定のデフォルト値を使う
+func (c *Client) Complete(ctx context.Context, messages []ChatMessage, params Params) (*Completion, error) {
 	log.Printf("[LLM Request] Model: %s", c.config.Model)
-	log.Printf("[LLM Request] User: %s", prompt)
+	for _, msg := range messages {
+		log.Printf("[LLM Request] %s: %s", msg.Role, msg.Content)
+	}
 
 	req := ChatRequest{
-		Model: c.config.Model,
-		Messages: []ChatMessage{
-			{Role: "user", Content: prompt},
-		},
+		Model:    c.config.Model,
+		Messages: messages,
 	}
+	c.config.Defaults.Merge(params).apply(&req)
+	return c.send(ctx, req)
+}
 
-	body, err := json.Marshal(req)
+func (c *Client) content(ctx context.Context, messages []ChatMessage) (string, error) {
+	completion, err := c.Complete(ctx, messages, Params{})
 	if err != nil {
-		return "", fmt.Errorf("failed to marshal request: %w", err)
+		return "", err
 	}
+	return completion.Content, nil
+}
 
-	httpReq, err := http.NewRequestWithContext(ctx, "POST", c.config.APIURL, bytes.NewReader(body))
+// Ping はLLM APIのエンドポイントに到達できるか確認する
+func (c *Client) Ping(ctx context.Context) error {
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodGet, c.config.APIURL, nil)
 	if err != nil {
-		return "", fmt.Errorf("failed to create request: %w", err)
+		return fmt.Errorf("failed to create request: %w", err)
 	}
-
-	httpReq.Header.Set("Content-Type", "application/json")
 	if c.config.APIKey != "" {
 		httpReq.Header.Set("Authorization", "Bearer "+c.config.APIKey)
 	}
 
 	resp, err := c.httpClient.Do(httpReq)
 	if err != nil {
-		return "", fmt.Errorf("failed to send request: %w", err)
+		return fmt.Errorf("failed to send request: %w", err)
 	}
 	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
 
-	respBody, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return "", fmt.Errorf("failed to read response: %w", err)
-	}
-
-	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("API returned status %d: %s", resp.StatusCode, string(respBody))
-	}
-
-	var chatResp ChatResponse
-	if err := json.Unmarshal(respBody, &chatResp); err != nil {
-		return "", fmt.Errorf("failed to unmarshal response: %w", err)
-	}
-
-	if chatResp.Error != nil {
-		return "", errors.New(chatResp.Error.Message)
-	}
-
-	if len(chatResp.Choices) == 0 {
-		return "", errors.New("no response from API")
+	// チャットエンドポイントへのGETは405等を返すことがあるため、5xxのみを異常とみなす
+	if resp.StatusCode >= http.StatusInternalServerError {
+		return fmt.Errorf("API returned status %d", resp.StatusCode)
 	}
-
-	return chatResp.Choices[0].Message.Content, nil
+	return nil
 }
 
-// ChatWithSystem はsystemプロンプト付きでチャットリクエストを送信
-func (c *Client) ChatWithSystem(ctx context.Context, systemPrompt, userPrompt string) (string, error) {
-	log.Printf("[LLM Request] Model: %s", c.config.Model)
-	log.Printf("[LLM Request] System: %s", systemPrompt)
-	log.Printf("[LLM Request] User: %s", userPrompt)
-
-	req := ChatRequest{
-		Model: c.config.Model,
-		Messages: []ChatMessage{
-			{Role: "system", Content: systemPrompt},
-			{Role: "user", Content: userPrompt},
-		},
+func (c *Client) send(ctx context.Context, req ChatRequest) (*Completion, error) {
+	start := time.Now()
+	completion, err := c.do(ctx, req)
+	metrics.LLMRequestDuration.Observe(time.Since(start).Seconds())
+	if err != nil {
+		metrics.LLMRequestErrors.Inc()
 	}
+	return completion, err
+}
 
+func (c *Client) do(ctx context.Context, req ChatRequest) (*Completion, error) {
 	body, err := json.Marshal(req)
 	if err != nil {
-		return "", fmt.Errorf("failed to marshal request: %w", err)
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
 	httpReq, err := http.NewRequestWithContext(ctx, "POST", c.config.APIURL, bytes.NewReader(body))
 	if err != nil {
-		return "", fmt.Errorf("failed to create request: %w", err)
+		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 
 	httpReq.Header.Set("Content-Type", "application/json")
@@ -124,31 +145,47 @@ func (c *Client) ChatWithSystem(ctx context.Context, systemPrompt, userPrompt st
 
 	resp, err := c.httpClient.Do(httpReq)
 	if err != nil {
-		return "", fmt.Errorf("failed to send request: %w", err)
+		return nil, fmt.Errorf("failed to send request: %w", err)
 	}
 	defer resp.Body.Close()
 
 	respBody, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return "", fmt.Errorf("failed to read response: %w", err)
+		return nil, fmt.Errorf("failed to read response: %w", err)
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("API returned status %d: %s", resp.StatusCode, string(respBody))
+		return nil, fmt.Errorf("API returned status %d: %s", resp.StatusCode, string(respBody))
 	}
 
 	var chatResp ChatResponse
 	if err := json.Unmarshal(respBody, &chatResp); err != nil {
-		return "", fmt.Errorf("failed to unmarshal response: %w", err)
+		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
 	}
 
 	if chatResp.Error != nil {
-		return "", errors.New(chatResp.Error.Message)
+		return nil, errors.New(chatResp.Error.Message)
+	}
+
+	if chatResp.Usage != nil {
+		metrics.LLMTokens.Add("prompt", float64(chatResp.Usage.PromptTokens))
+		metrics.LLMTokens.Add("completion", float64(chatResp.Usage.CompletionTokens))
 	}
 
 	if len(chatResp.Choices) == 0 {
-		return "", errors.New("no response from API")
+		return nil, errors.New("no response from API")
 	}
 
-	return chatResp.Choices[0].Message.Content, nil
+	completion := &Completion{
+		Content:      chatResp.Choices[0].Message.Content,
+		FinishReason: chatResp.Choices[0].FinishReason,
+		Model:        chatResp.Model,
+	}
+	if completion.Model == "" {
+		completion.Model = req.Model
+	}
+	if chatResp.Usage != nil {
+		completion.Usage = *chatResp.Usage
+	}
+	return completion, nil
 }
@@ -0,0 +1,57 @@
+package llm
+
+// Params は生成パラメータ。nil・空の項目はAPIのデフォルトに任せる
+type Params struct {
+	Temperature *float64
+	TopP        *float64
+	MaxTokens   *int
+	Stop        []string
+	Seed        *int64
+	// ResponseFormat は "text" または "json_object"
+	ResponseFormat string
+}
+
+// Merge はoverrideで設定されている項目を優先してパラメータを合成する
+func (p Params) Merge(override Params) Params {
+	merged := p
+	if override.Temperature != nil {
+		merged.Temperature = override.Temperature
+	}
+	if override.TopP != nil {
+		merged.TopP = override.TopP
+	}
+	if override.MaxTokens != nil {
+		merged.MaxTokens = override.MaxTokens
+	}
+	if override.Stop != nil {
+		merged.Stop = override.Stop
+	}
+	if override.Seed != nil {
+		merged.Seed = override.Seed
+	}
+	if override.ResponseFormat != "" {
+		merged.ResponseFormat = override.ResponseFormat
+	}
+	return merged
+}
+
+func (p Params) apply(req *ChatRequest) {
+	req.Temperature = p.Temperature
+	req.TopP = p.TopP
+	req.MaxTokens = p.MaxTokens
+	req.Stop = p.Stop
+	req.Seed = p.Seed
+	if p.ResponseFormat != "" {
+		req.ResponseFormat = &ResponseFormat{Type: p.ResponseFormat}
+	}
+}
+
+// Float64 はfloat64のポインタを返す
+func Float64(v float64) *float64 {
+	return &v
+}
+
+// Int はintのポインタを返す
+func Int(v int) *int {
+	return &v
+}
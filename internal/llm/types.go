@@ -6,19 +6,43 @@ type ChatMessage struct {
 	Content string `json:"content"`
 }
 
+// ResponseFormat は応答形式の指定（"text" / "json_object"）
+type ResponseFormat struct {
+	Type string `json:"type"`
+}
+
 // ChatRequest はOpenAI Chat APIリクエスト形式
 type ChatRequest struct {
-	Model    string        `json:"model"`
-	Messages []ChatMessage `json:"messages"`
+	Model          string          `json:"model"`
+	Messages       []ChatMessage   `json:"messages"`
+	Temperature    *float64        `json:"temperature,omitempty"`
+	TopP           *float64        `json:"top_p,omitempty"`
+	MaxTokens      *int            `json:"max_tokens,omitempty"`
+	Stop           []string        `json:"stop,omitempty"`
+	Seed           *int64          `json:"seed,omitempty"`
+	ResponseFormat *ResponseFormat `json:"response_format,omitempty"`
 }
 
 // ChatResponse はOpenAI Chat APIレスポンス形式
 type ChatResponse struct {
-	ID      string `json:"id"`
-	Choices []struct {
-		Message ChatMessage `json:"message"`
-	} `json:"choices"`
-	Error *APIError `json:"error,omitempty"`
+	ID      string    `json:"id"`
+	Model   string    `json:"model,omitempty"`
+	Choices []Choice  `json:"choices"`
+	Usage   *Usage    `json:"usage,omitempty"`
+	Error   *APIError `json:"error,omitempty"`
+}
+
+// Choice はレスポンスの生成候補
+type Choice struct {
+	Message      ChatMessage `json:"message"`
+	FinishReason string      `json:"finish_reason,omitempty"`
+}
+
+// Usage はAPIが報告するトークン使用量
+type Usage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	TotalTokens      int `json:"total_tokens"`
 }
 
 // APIError はAPIエラーレスポンス
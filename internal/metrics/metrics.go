@@ -0,0 +1,52 @@
+package metrics
+
+// Default はアプリケーション全体で共有するRegistry
+var Default = NewRegistry()
+
+var (
+	// MessagesIngested は保存した登録済みユーザーのメッセージ数
+	MessagesIngested = Default.NewCounter(
+		"doppelcord_messages_ingested_total",
+		"Number of messages from registered users stored in the database.",
+	)
+
+	// MessageSaveErrors はメッセージ保存に失敗した回数
+	MessageSaveErrors = Default.NewCounter(
+		"doppelcord_message_save_errors_total",
+		"Number of failed message saves.",
+	)
+
+	// LLMRequestDuration はLLM APIのレイテンシ
+	LLMRequestDuration = Default.NewHistogram(
+		"doppelcord_llm_request_duration_seconds",
+		"Latency of LLM API requests in seconds.",
+		DefaultBuckets,
+	)
+
+	// LLMRequestErrors はLLM API呼び出しに失敗した回数
+	LLMRequestErrors = Default.NewCounter(
+		"doppelcord_llm_request_errors_total",
+		"Number of failed LLM API requests.",
+	)
+
+	// LLMTokens はLLM APIが報告したトークン数（type: prompt / completion）
+	LLMTokens = Default.NewCounterVec(
+		"doppelcord_llm_tokens_total",
+		"Number of tokens reported by the LLM API.",
+		"type",
+	)
+
+	// CommandInvocations はコマンド名ごとの実行回数
+	CommandInvocations = Default.NewCounterVec(
+		"doppelcord_command_invocations_total",
+		"Number of application command invocations by name.",
+		"command",
+	)
+
+	// QuotaRejections は利用制限により生成を断った回数（reason: cooldown / daily / monthly）
+	QuotaRejections = Default.NewCounterVec(
+		"doppelcord_quota_rejections_total",
+		"Number of generations rejected by per-user usage limits.",
+		"reason",
+	)
+)
@@ -0,0 +1,229 @@
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+type collector interface {
+	write(w *bufio.Writer)
+}
+
+// Registry はメトリクスを保持し、Prometheusテキスト形式で出力する
+type Registry struct {
+	mu         sync.Mutex
+	collectors []collector
+}
+
+// NewRegistry は空のRegistryを生成
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+func (r *Registry) register(c collector) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.collectors = append(r.collectors, c)
+}
+
+// Write は全メトリクスをPrometheusテキスト形式で書き出す
+func (r *Registry) Write(w io.Writer) error {
+	r.mu.Lock()
+	collectors := append([]collector(nil), r.collectors...)
+	r.mu.Unlock()
+
+	bw := bufio.NewWriter(w)
+	for _, c := range collectors {
+		c.write(bw)
+	}
+	return bw.Flush()
+}
+
+// Handler は/metrics用のHTTPハンドラーを返す
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		if err := r.Write(w); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+	})
+}
+
+// Counter は単調増加するカウンター
+type Counter struct {
+	name, help string
+	mu         sync.Mutex
+	value      float64
+}
+
+// NewCounter はCounterを生成して登録
+func (r *Registry) NewCounter(name, help string) *Counter {
+	c := &Counter{name: name, help: help}
+	r.register(c)
+	return c
+}
+
+// Inc はカウンターを1増やす
+func (c *Counter) Inc() {
+	c.Add(1)
+}
+
+// Add はカウンターをv増やす
+func (c *Counter) Add(v float64) {
+	c.mu.Lock()
+	c.value += v
+	c.mu.Unlock()
+}
+
+func (c *Counter) write(w *bufio.Writer) {
+	c.mu.Lock()
+	v := c.value
+	c.mu.Unlock()
+
+	writeHeader(w, c.name, c.help, "counter")
+	fmt.Fprintf(w, "%s %s\n", c.name, formatFloat(v))
+}
+
+// CounterVec はラベル1つで分割されたカウンター
+type CounterVec struct {
+	name, help, label string
+	mu                sync.Mutex
+	values            map[string]float64
+}
+
+// NewCounterVec はCounterVecを生成して登録
+func (r *Registry) NewCounterVec(name, help, label string) *CounterVec {
+	c := &CounterVec{name: name, help: help, label: label, values: make(map[string]float64)}
+	r.register(c)
+	return c
+}
+
+// Inc は指定ラベル値のカウンターを1増やす
+func (c *CounterVec) Inc(labelValue string) {
+	c.Add(labelValue, 1)
+}
+
+// Add は指定ラベル値のカウンターをv増やす
+func (c *CounterVec) Add(labelValue string, v float64) {
+	c.mu.Lock()
+	c.values[labelValue] += v
+	c.mu.Unlock()
+}
+
+func (c *CounterVec) write(w *bufio.Writer) {
+	c.mu.Lock()
+	keys := make([]string, 0, len(c.values))
+	for k := range c.values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	values := make([]float64, len(keys))
+	for i, k := range keys {
+		values[i] = c.values[k]
+	}
+	c.mu.Unlock()
+
+	writeHeader(w, c.name, c.help, "counter")
+	for i, k := range keys {
+		fmt.Fprintf(w, "%s{%s=\"%s\"} %s\n", c.name, c.label, escapeLabel(k), formatFloat(values[i]))
+	}
+}
+
+// Histogram は観測値の分布を記録する
+type Histogram struct {
+	name, help string
+	buckets    []float64
+	mu         sync.Mutex
+	counts     []uint64
+	sum        float64
+	count      uint64
+}
+
+// DefaultBuckets はレイテンシ（秒）向けのデフォルトバケット
+var DefaultBuckets = []float64{0.1, 0.25, 0.5, 1, 2.5, 5, 10, 20, 30, 60}
+
+// NewHistogram はHistogramを生成して登録
+func (r *Registry) NewHistogram(name, help string, buckets []float64) *Histogram {
+	b := append([]float64(nil), buckets...)
+	sort.Float64s(b)
+	h := &Histogram{name: name, help: help, buckets: b, counts: make([]uint64, len(b))}
+	r.register(h)
+	return h
+}
+
+// Observe は値を1つ記録する
+func (h *Histogram) Observe(v float64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for i, upper := range h.buckets {
+		if v <= upper {
+			h.counts[i]++
+		}
+	}
+	h.sum += v
+	h.count++
+}
+
+func (h *Histogram) write(w *bufio.Writer) {
+	h.mu.Lock()
+	counts := append([]uint64(nil), h.counts...)
+	sum, count := h.sum, h.count
+	h.mu.Unlock()
+
+	writeHeader(w, h.name, h.help, "histogram")
+	for i, upper := range h.buckets {
+		fmt.Fprintf(w, "%s_bucket{le=\"%s\"} %d\n", h.name, formatFloat(upper), counts[i])
+	}
+	fmt.Fprintf(w, "%s_bucket{le=\"+Inf\"} %d\n", h.name, count)
+	fmt.Fprintf(w, "%s_sum %s\n", h.name, formatFloat(sum))
+	fmt.Fprintf(w, "%s_count %d\n", h.name, count)
+}
+
+// GaugeFunc は出力時に関数を呼び出して値を取得するゲージ
+type GaugeFunc struct {
+	name, help string
+	fn         func() float64
+}
+
+// NewGaugeFunc はGaugeFuncを生成して登録
+func (r *Registry) NewGaugeFunc(name, help string, fn func() float64) *GaugeFunc {
+	g := &GaugeFunc{name: name, help: help, fn: fn}
+	r.register(g)
+	return g
+}
+
+func (g *GaugeFunc) write(w *bufio.Writer) {
+	writeHeader(w, g.name, g.help, "gauge")
+	fmt.Fprintf(w, "%s %s\n", g.name, formatFloat(g.fn()))
+}
+
+func writeHeader(w *bufio.Writer, name, help, typ string) {
+	fmt.Fprintf(w, "# HELP %s %s\n", name, help)
+	fmt.Fprintf(w, "# TYPE %s %s\n", name, typ)
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapeLabel(v string) string {
+	return labelEscaper.Replace(v)
+}
@@ -0,0 +1,227 @@
+package prompt
+
+import (
+	"bytes"
+	"fmt"
+	"slices"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/chun37/doppelcord/internal/domain"
+)
+
+const (
+	defaultSystemTemplate = `あなたは以下のメッセージ履歴を持つDiscordユーザー{{if .DisplayName}}「{{.DisplayName}}」{{end}}になりきってください。
+{{- if .Persona}}
+
+## 人物像:
+{{.Persona}}
+{{- end}}
+
+## このユーザーの発言履歴（{{if .Period}}{{.Period}}、{{end}}新しい順）:
+{{.History}}
+{{- if .Dialogues}}
+
+## 他の人への返信の例（古い順、「相手」への「本人」の返信）:
+{{.Dialogues}}
+{{- end}}
+{{- if .Related}}
+
+## 話題に関連する過去の発言:
+{{.Related}}
+{{- end}}
+{{- if .Examples}}
+
+## 本人らしいと評価された過去の生成例:
+{{.Examples}}
+{{- end}}
+{{- if or .ChannelName .TimeOfDay}}
+
+## 状況:
+{{- if .ChannelName}}
+- チャンネル: #{{.ChannelName}}
+{{- end}}
+{{- if .TimeOfDay}}
+- 時間帯: {{.TimeOfDay}}
+{{- end}}
+{{- end}}
+
+## 指示:
+- 上記の発言履歴から、このユーザーの文体、口調、言葉遣い、絵文字の使い方、話題の傾向を分析してください
+- このユーザーとして自然にメッセージを送信してください
+- 履歴にある特徴的な表現や癖があれば再現してください
+{{- if .Dialogues}}
+- 返信の例から、このユーザーが他の人の発言にどう反応するかも参考にしてください
+{{- end}}
+- 不自然に履歴を引用したり、なりきりであることを示したりしないでください
+{{- if .Period}}
+- 履歴の期間（{{.Period}}）当時のこのユーザーとして、その頃の口調や話題で発言してください
+{{- end}}`
+
+	defaultUserTemplate = `{{if .ReplyTo -}}
+{{if .Conversation}}これまでの会話（古い順）:
+{{.Conversation}}
+
+{{end -}}
+{{if .ReplyAuthor}}{{.ReplyAuthor}}さんの{{end}}次のメッセージに返信してください。
+「{{.ReplyTo}}」
+{{- else if .Topic -}}
+「{{.Topic}}」について何か一言メッセージを送ってください。
+{{- else -}}
+何か一言メッセージを送ってください。
+{{- end}}`
+
+	historySeparator = "\n---\n"
+)
+
+// DefaultTemplate はオーバーライドが設定されていない場合に使うテンプレート
+var DefaultTemplate = Template{
+	System: defaultSystemTemplate,
+	User:   defaultUserTemplate,
+}
+
+// Data はテンプレートに渡す変数
+type Data struct {
+	// History は整形済みの発言履歴
+	History string
+	// Dialogues は他のユーザーへの返信の例（古い順、"相手: 本文\n本人: 本文" の組、任意）
+	Dialogues string
+	// Related は話題や返信先に関連する過去の発言（任意）
+	Related string
+	// Examples は本人らしいと評価された過去の生成（few-shot例、任意）
+	Examples string
+	// Persona は人物像や追加の指示（任意）
+	Persona string
+	// ChannelName は生成先のチャンネル名
+	ChannelName string
+	// DisplayName はなりきる対象の表示名
+	DisplayName string
+	// TimeOfDay は生成時点の時間帯（朝・昼など）
+	TimeOfDay string
+	// Topic は生成するメッセージの話題（任意）
+	Topic string
+	// ReplyTo は返信先のメッセージ本文（任意）
+	ReplyTo string
+	// ReplyAuthor は返信先のメッセージの送信者の表示名
+	ReplyAuthor string
+	// Conversation は返信先に至るまでの返信の連鎖（古い順、"名前: 本文" の行）
+	Conversation string
+	// Period は履歴を絞り込んだ期間（例: "2025-03-01〜2025-05-31"、任意）
+	Period string
+}
+
+// Template はsystemプロンプトとuserプロンプトのtext/templateテンプレート
+type Template struct {
+	System string
+	User   string
+}
+
+// Render はテンプレートに変数を埋め込んでsystemプロンプトとuserプロンプトを返す
+func (t Template) Render(data Data) (system, user string, err error) {
+	system, err = render("system", t.System, data)
+	if err != nil {
+		return "", "", err
+	}
+	user, err = render("user", t.User, data)
+	if err != nil {
+		return "", "", err
+	}
+	return system, user, nil
+}
+
+// Validate はテンプレートが解析・実行できるか確認する
+func (t Template) Validate() error {
+	_, _, err := t.Render(Data{
+		History:      "example" + historySeparator,
+		Dialogues:    "相手: example\n本人: example" + historySeparator,
+		Related:      "example" + historySeparator,
+		Examples:     "example" + historySeparator,
+		Persona:      "example",
+		ChannelName:  "general",
+		DisplayName:  "example",
+		TimeOfDay:    "昼",
+		Topic:        "example",
+		ReplyTo:      "example",
+		ReplyAuthor:  "example",
+		Conversation: "example: example",
+		Period:       "2025-03-01〜2025-05-31",
+	})
+	return err
+}
+
+func render(name, text string, data Data) (string, error) {
+	tmpl, err := template.New(name).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse %s template: %w", name, err)
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("failed to execute %s template: %w", name, err)
+	}
+	return buf.String(), nil
+}
+
+// FormatHistory は発言履歴（新しい順）をテンプレート用の文字列に整形する。
+// 履歴はmaxCharsを超えない範囲で含める
+func FormatHistory(messages []*domain.Message, maxChars int) string {
+	var sb strings.Builder
+	charCount := 0
+
+	for _, msg := range messages {
+		if charCount+len(msg.Content) > maxChars {
+			break
+		}
+		sb.WriteString(msg.Content)
+		sb.WriteString(historySeparator)
+		charCount += len(msg.Content) + len(historySeparator)
+	}
+
+	return sb.String()
+}
+
+// FormatDialogues は他のユーザーへの返信（新しい順）を "相手: 本文\n本人: 本文" の組にして古い順に並べる。
+// 返信はmaxCharsを超えない範囲で新しいものから含める
+func FormatDialogues(replies []*domain.Message, maxChars int) string {
+	var pairs []string
+	charCount := 0
+
+	for _, msg := range replies {
+		pair := "相手: " + msg.ReplyToContent + "\n本人: " + msg.Content + historySeparator
+		if charCount+len(pair) > maxChars {
+			break
+		}
+		pairs = append(pairs, pair)
+		charCount += len(pair)
+	}
+
+	slices.Reverse(pairs)
+	return strings.Join(pairs, "")
+}
+
+// FormatExamples は生成例をテンプレート用の文字列に整形する
+func FormatExamples(examples []string) string {
+	var sb strings.Builder
+	for _, example := range examples {
+		sb.WriteString(example)
+		sb.WriteString(historySeparator)
+	}
+	return sb.String()
+}
+
+// TimeOfDay は時刻を時間帯の表現に変換する
+func TimeOfDay(t time.Time) string {
+	switch h := t.Hour(); {
+	case h >= 5 && h < 10:
+		return "朝"
+	case h >= 10 && h < 16:
+		return "昼"
+	case h >= 16 && h < 19:
+		return "夕方"
+	case h >= 19 && h < 24:
+		return "夜"
+	default:
+		return "深夜"
+	}
+}
@@ -0,0 +1,284 @@
+package prompt
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/chun37/doppelcord/internal/domain"
+	"github.com/chun37/doppelcord/internal/llm"
+)
+
+// testMessages は新しい順の発言履歴。2件目は他のユーザーへの返信
+var testMessages = []*domain.Message{
+	{MessageID: "3", Content: "おやすみ〜"},
+	{MessageID: "2", Content: "それな", ReplyToAuthorID: "100", ReplyToContent: "今日寒くない？"},
+	{MessageID: "1", Content: "おはよう"},
+}
+
+func TestFormatHistory(t *testing.T) {
+	tests := []struct {
+		name     string
+		maxChars int
+		want     string
+	}{
+		{
+			name:     "all",
+			maxChars: 1000,
+			want:     "おやすみ〜\n---\nそれな\n---\nおはよう\n---\n",
+		},
+		{
+			// 1件目（15バイト）と区切り（5バイト）のあと、2件目（9バイト）は収まらない
+			name:     "truncated",
+			maxChars: 28,
+			want:     "おやすみ〜\n---\n",
+		},
+		{
+			name:     "none",
+			maxChars: 0,
+			want:     "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := FormatHistory(testMessages, tt.maxChars); got != tt.want {
+				t.Errorf("FormatHistory() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatDialogues(t *testing.T) {
+	replies := []*domain.Message{
+		{MessageID: "5", Content: "いいね", ReplyToContent: "ラーメン行こう"},
+		testMessages[1],
+	}
+	tests := []struct {
+		name     string
+		maxChars int
+		want     string
+	}{
+		{
+			name:     "oldest first",
+			maxChars: 1000,
+			want:     "相手: 今日寒くない？\n本人: それな\n---\n相手: ラーメン行こう\n本人: いいね\n---\n",
+		},
+		{
+			// 新しい返信から含める
+			name:     "truncated",
+			maxChars: 60,
+			want:     "相手: ラーメン行こう\n本人: いいね\n---\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := FormatDialogues(replies, tt.maxChars); got != tt.want {
+				t.Errorf("FormatDialogues() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatChatHistory(t *testing.T) {
+	tests := []struct {
+		name     string
+		maxChars int
+		want     []llm.ChatMessage
+	}{
+		{
+			name:     "all",
+			maxChars: 1000,
+			want: []llm.ChatMessage{
+				{Role: "user", Content: "何か一言メッセージを送ってください。"},
+				{Role: "assistant", Content: "おはよう"},
+				{Role: "user", Content: "次のメッセージに返信してください。\n「今日寒くない？」"},
+				{Role: "assistant", Content: "それな"},
+				{Role: "user", Content: "何か一言メッセージを送ってください。"},
+				{Role: "assistant", Content: "おやすみ〜"},
+			},
+		},
+		{
+			// 1件目は指示（54バイト）と発言（15バイト）で69バイト。2件目は収まらない
+			name:     "truncated",
+			maxChars: 100,
+			want: []llm.ChatMessage{
+				{Role: "user", Content: "何か一言メッセージを送ってください。"},
+				{Role: "assistant", Content: "おやすみ〜"},
+			},
+		},
+		{
+			name:     "none",
+			maxChars: 0,
+			want:     nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := FormatChatHistory(testMessages, tt.maxChars); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("FormatChatHistory() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplyHistory(t *testing.T) {
+	base := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	// 返信は別のクエリで取得するため、履歴と同じメッセージでもポインタは異なる
+	messages := []*domain.Message{
+		{MessageID: "3", Content: "おやすみ〜", CreatedAt: base.Add(3 * time.Hour)},
+		{MessageID: "2", Content: "それな", ReplyToContent: "今日寒くない？", CreatedAt: base.Add(2 * time.Hour)},
+		{MessageID: "1", Content: "おはよう", CreatedAt: base.Add(time.Hour)},
+	}
+	replies := []*domain.Message{
+		{MessageID: "4", Content: "いいね", ReplyToContent: "ラーメン行こう", CreatedAt: base.Add(4 * time.Hour)},
+		{MessageID: "2", Content: "それな", ReplyToContent: "今日寒くない？", CreatedAt: base.Add(2 * time.Hour)},
+	}
+	const dialogues = "相手: 今日寒くない？\n本人: それな\n---\n相手: ラーメン行こう\n本人: いいね\n---\n"
+
+	tests := []struct {
+		name          string
+		strategy      Strategy
+		messages      []*domain.Message
+		replies       []*domain.Message
+		maxChars      int
+		wantHistory   string
+		wantDialogues string
+		wantTurns     []llm.ChatMessage
+	}{
+		{
+			// 返信は会話例に含め、履歴には重複させない
+			name:          "template",
+			strategy:      StrategyTemplate,
+			messages:      messages,
+			replies:       replies,
+			maxChars:      1000,
+			wantHistory:   "おやすみ〜\n---\nおはよう\n---\n",
+			wantDialogues: dialogues,
+		},
+		{
+			// 会話例の分を差し引いた28バイトでは、履歴は1件目（20バイト）まで
+			name:          "template over budget",
+			strategy:      StrategyTemplate,
+			messages:      messages,
+			replies:       replies,
+			maxChars:      len(dialogues) + 28,
+			wantHistory:   "おやすみ〜\n---\n",
+			wantDialogues: dialogues,
+		},
+		{
+			name:     "template empty",
+			strategy: StrategyTemplate,
+			maxChars: 1000,
+		},
+		{
+			name:     "default is template",
+			messages: messages[:1],
+			maxChars: 1000,
+			// 空の戦略はテンプレート形式として扱う
+			wantHistory: "おやすみ〜\n---\n",
+		},
+		{
+			// 履歴と返信を重複なく時系列に並べる
+			name:        "chat",
+			strategy:    StrategyChat,
+			messages:    messages,
+			replies:     replies,
+			maxChars:    1000,
+			wantHistory: ChatHistoryNote,
+			wantTurns: []llm.ChatMessage{
+				{Role: "user", Content: "何か一言メッセージを送ってください。"},
+				{Role: "assistant", Content: "おはよう"},
+				{Role: "user", Content: "次のメッセージに返信してください。\n「今日寒くない？」"},
+				{Role: "assistant", Content: "それな"},
+				{Role: "user", Content: "何か一言メッセージを送ってください。"},
+				{Role: "assistant", Content: "おやすみ〜"},
+				{Role: "user", Content: "次のメッセージに返信してください。\n「ラーメン行こう」"},
+				{Role: "assistant", Content: "いいね"},
+			},
+		},
+		{
+			// 最新の返信は指示（79バイト）と発言（9バイト）で88バイト。2件目は収まらない
+			name:        "chat over budget",
+			strategy:    StrategyChat,
+			messages:    messages,
+			replies:     replies,
+			maxChars:    100,
+			wantHistory: ChatHistoryNote,
+			wantTurns: []llm.ChatMessage{
+				{Role: "user", Content: "次のメッセージに返信してください。\n「ラーメン行こう」"},
+				{Role: "assistant", Content: "いいね"},
+			},
+		},
+		{
+			name:        "chat empty",
+			strategy:    StrategyChat,
+			maxChars:    1000,
+			wantHistory: ChatHistoryNote,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var data Data
+			turns := ApplyHistory(tt.strategy, &data, tt.messages, tt.replies, tt.maxChars, 1000)
+			if data.History != tt.wantHistory {
+				t.Errorf("History = %q, want %q", data.History, tt.wantHistory)
+			}
+			if data.Dialogues != tt.wantDialogues {
+				t.Errorf("Dialogues = %q, want %q", data.Dialogues, tt.wantDialogues)
+			}
+			if !reflect.DeepEqual(turns, tt.wantTurns) {
+				t.Errorf("turns = %q, want %q", turns, tt.wantTurns)
+			}
+		})
+	}
+}
+
+func TestComposeChat(t *testing.T) {
+	tests := []struct {
+		name  string
+		turns []llm.ChatMessage
+		want  []llm.ChatMessage
+	}{
+		{
+			name: "no history",
+			want: []llm.ChatMessage{
+				{Role: "system", Content: "あなたは本人です。"},
+				{Role: "user", Content: "発言してください。"},
+			},
+		},
+		{
+			name: "history",
+			turns: []llm.ChatMessage{
+				{Role: "user", Content: "何か一言メッセージを送ってください。"},
+				{Role: "assistant", Content: "おはよう"},
+			},
+			want: []llm.ChatMessage{
+				{Role: "system", Content: "あなたは本人です。"},
+				{Role: "user", Content: "何か一言メッセージを送ってください。"},
+				{Role: "assistant", Content: "おはよう"},
+				{Role: "user", Content: "発言してください。"},
+			},
+		},
+		{
+			// 同じroleが続く場合は1つにまとめる
+			name: "consecutive roles",
+			turns: []llm.ChatMessage{
+				{Role: "assistant", Content: "おはよう"},
+				{Role: "assistant", Content: "おやすみ〜"},
+				{Role: "user", Content: "前置き"},
+			},
+			want: []llm.ChatMessage{
+				{Role: "system", Content: "あなたは本人です。"},
+				{Role: "assistant", Content: "おはよう\n\nおやすみ〜"},
+				{Role: "user", Content: "前置き\n\n発言してください。"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ComposeChat("あなたは本人です。", tt.turns, "発言してください。"); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ComposeChat() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
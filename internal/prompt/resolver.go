@@ -0,0 +1,61 @@
+package prompt
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/chun37/doppelcord/internal/domain"
+	"github.com/chun37/doppelcord/internal/repository"
+)
+
+// Resolved は解決されたテンプレートとその出所
+type Resolved struct {
+	Template Template
+	// Source は "default" / "guild:v3" / "user:v1" のような出所の表示
+	Source string
+	// Override は元になったオーバーライド。デフォルトの場合はnil
+	Override *domain.PromptTemplate
+}
+
+// Resolver はユーザー > ギルド > デフォルトの優先順でテンプレートを解決する
+type Resolver struct {
+	repo repository.PromptTemplateRepository
+}
+
+// NewResolver は新しいResolverを生成
+func NewResolver(repo repository.PromptTemplateRepository) *Resolver {
+	return &Resolver{repo: repo}
+}
+
+// Resolve はユーザーとギルドに適用されるテンプレートを返す
+func (r *Resolver) Resolve(ctx context.Context, guildID, userID string) (*Resolved, error) {
+	if userID != "" {
+		tmpl, err := r.repo.FindLatest(ctx, domain.PromptTemplateScopeUser, userID)
+		if err != nil {
+			return nil, err
+		}
+		if tmpl != nil {
+			return fromOverride(tmpl), nil
+		}
+	}
+
+	if guildID != "" {
+		tmpl, err := r.repo.FindLatest(ctx, domain.PromptTemplateScopeGuild, guildID)
+		if err != nil {
+			return nil, err
+		}
+		if tmpl != nil {
+			return fromOverride(tmpl), nil
+		}
+	}
+
+	return &Resolved{Template: DefaultTemplate, Source: "default"}, nil
+}
+
+func fromOverride(tmpl *domain.PromptTemplate) *Resolved {
+	return &Resolved{
+		Template: Template{System: tmpl.SystemTemplate, User: tmpl.UserTemplate},
+		Source:   fmt.Sprintf("%s:v%d", tmpl.Scope, tmpl.Version),
+		Override: tmpl,
+	}
+}
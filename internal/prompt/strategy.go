@@ -0,0 +1,124 @@
+package prompt
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/chun37/doppelcord/internal/domain"
+	"github.com/chun37/doppelcord/internal/llm"
+)
+
+// Strategy は発言履歴をLLMに渡す形式
+type Strategy string
+
+const (
+	// StrategyTemplate は履歴を "---" 区切りの1つのテキストとしてsystemプロンプトに含める（既定）
+	StrategyTemplate Strategy = "template"
+	// StrategyChat は履歴を古い順の会話として、本人の発言をassistant、その前の文脈をuserのメッセージにする
+	StrategyChat Strategy = "chat"
+)
+
+// ChatHistoryNote はStrategyChatの場合にテンプレートの {{.History}} に入れる説明
+const ChatHistoryNote = "（過去の発言は、このあとの会話でassistantの発言として示します）"
+
+const (
+	// chatMonologueContext は返信ではない発言の前に置くuserのメッセージ
+	chatMonologueContext = "何か一言メッセージを送ってください。"
+	// chatReplyContextFormat は返信の前に置くuserのメッセージ（返信先の本文を埋め込む）
+	chatReplyContextFormat = "次のメッセージに返信してください。\n「%s」"
+)
+
+// FormatChatHistory は発言履歴（新しい順）を古い順の会話に整形する。本人の発言をassistantとし、
+// その前に返信先の本文（返信でなければ一言を促す指示）をuserとして置く。
+// 履歴はmaxCharsを超えない範囲で新しいものから含める
+func FormatChatHistory(messages []*domain.Message, maxChars int) []llm.ChatMessage {
+	var turns []llm.ChatMessage
+	charCount := 0
+
+	for _, msg := range messages {
+		context := chatMonologueContext
+		if msg.ReplyToContent != "" {
+			context = fmt.Sprintf(chatReplyContextFormat, msg.ReplyToContent)
+		}
+		if charCount+len(context)+len(msg.Content) > maxChars {
+			break
+		}
+		// 後で並びを反転するため、assistant・userの順に追加する
+		turns = append(turns,
+			llm.ChatMessage{Role: "assistant", Content: msg.Content},
+			llm.ChatMessage{Role: "user", Content: context},
+		)
+		charCount += len(context) + len(msg.Content)
+	}
+
+	slices.Reverse(turns)
+	return turns
+}
+
+// ApplyHistory は発言履歴と他のユーザーへの返信を戦略に応じた形式でプロンプトに含める。
+// StrategyChatの場合は会話形式の履歴を返し、それ以外はdataのHistory・Dialoguesに設定してnilを返す。
+// 履歴は返信の例と合わせてmaxCharsまで、返信の例はdialogueCharsまで含める
+func ApplyHistory(strategy Strategy, data *Data, messages, replies []*domain.Message, maxChars, dialogueChars int) []llm.ChatMessage {
+	if strategy == StrategyChat {
+		// 返信は返信先の本文をuserの発言にできるため、履歴と合わせて1つの会話として並べる
+		data.History = ChatHistoryNote
+		return FormatChatHistory(mergeMessages(messages, replies), maxChars)
+	}
+
+	// 他のユーザーへの返信は会話例として含め、履歴には重複させない
+	data.Dialogues = FormatDialogues(replies, dialogueChars)
+	data.History = FormatHistory(excludeMessages(messages, replies), maxChars-len(data.Dialogues))
+	return nil
+}
+
+// ComposeChat はsystemプロンプト、会話形式の履歴、userプロンプトの順にメッセージを並べる。
+// roleが交互でないと受け付けないチャットテンプレートがあるため、同じroleが続く場合は1つのメッセージにまとめる
+func ComposeChat(systemPrompt string, turns []llm.ChatMessage, userPrompt string) []llm.ChatMessage {
+	chat := make([]llm.ChatMessage, 0, len(turns)+2)
+	add := func(msg llm.ChatMessage) {
+		if n := len(chat); n > 0 && chat[n-1].Role == msg.Role {
+			chat[n-1].Content += "\n\n" + msg.Content
+			return
+		}
+		chat = append(chat, msg)
+	}
+
+	add(llm.ChatMessage{Role: "system", Content: systemPrompt})
+	for _, turn := range turns {
+		add(turn)
+	}
+	add(llm.ChatMessage{Role: "user", Content: userPrompt})
+	return chat
+}
+
+// excludeMessages はmessagesからexcludedに含まれるメッセージを除く。
+// メッセージIDのない発言（評価用の入力ファイルなど）は同じ値かどうかで判定する
+func excludeMessages(messages, excluded []*domain.Message) []*domain.Message {
+	if len(excluded) == 0 {
+		return messages
+	}
+	ids := make(map[string]bool, len(excluded))
+	same := make(map[*domain.Message]bool, len(excluded))
+	for _, msg := range excluded {
+		if msg.MessageID != "" {
+			ids[msg.MessageID] = true
+		}
+		same[msg] = true
+	}
+	kept := make([]*domain.Message, 0, len(messages))
+	for _, msg := range messages {
+		if !same[msg] && !(msg.MessageID != "" && ids[msg.MessageID]) {
+			kept = append(kept, msg)
+		}
+	}
+	return kept
+}
+
+// mergeMessages は2つの発言の一覧を重複を除いて新しい順にまとめる
+func mergeMessages(messages, more []*domain.Message) []*domain.Message {
+	merged := append(slices.Clone(messages), excludeMessages(more, messages)...)
+	slices.SortStableFunc(merged, func(a, b *domain.Message) int {
+		return b.CreatedAt.Compare(a.CreatedAt)
+	})
+	return merged
+}
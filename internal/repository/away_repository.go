@@ -0,0 +1,18 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/chun37/doppelcord/internal/domain"
+)
+
+// AwayRepository はユーザーの不在設定を管理する
+type AwayRepository interface {
+	// Set は不在設定を作成または上書きする
+	Set(ctx context.Context, status *domain.AwayStatus) error
+	// Clear は不在設定を削除する。設定がなければfalse
+	Clear(ctx context.Context, discordID string) (bool, error)
+	// FindActive はdiscordIDsのうち、nowの時点で不在中のユーザーの設定を返す
+	FindActive(ctx context.Context, discordIDs []string, now time.Time) ([]*domain.AwayStatus, error)
+}
@@ -68,3 +68,11 @@ func (r *CachedUserRepository) GetAllDiscordIDs(ctx context.Context) ([]string,
 	}
 	return ids, nil
 }
+
+// Len はキャッシュ済みの登録ユーザー数を返す
+func (r *CachedUserRepository) Len() int {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return len(r.registered)
+}
@@ -0,0 +1,85 @@
+package cached
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/chun37/doppelcord/internal/domain"
+	"github.com/chun37/doppelcord/internal/repository"
+)
+
+const listenRetryInterval = 5 * time.Second
+
+// Sync は他のインスタンスでの登録・削除をキャッシュに反映し続ける。
+// LISTEN/NOTIFYによる変更通知を購読し、購読開始時（再接続時を含む）と
+// reloadIntervalごとに全件を再読み込みする。ctxがキャンセルされるまでブロックする
+func (r *CachedUserRepository) Sync(ctx context.Context, listener repository.UserChangeListener, reloadInterval time.Duration) {
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	wg.Go(func() {
+		r.reloadPeriodically(ctx, reloadInterval)
+	})
+
+	for {
+		err := listener.Listen(ctx,
+			func() { r.reload(ctx, "listener connected") },
+			r.apply,
+		)
+		if ctx.Err() != nil {
+			return
+		}
+		log.Printf("User change listener stopped: %v (retrying in %s)", err, listenRetryInterval)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(listenRetryInterval):
+		}
+	}
+}
+
+func (r *CachedUserRepository) reloadPeriodically(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			r.reload(ctx, "periodic")
+		}
+	}
+}
+
+func (r *CachedUserRepository) reload(ctx context.Context, reason string) {
+	if err := r.LoadAll(ctx); err != nil {
+		if ctx.Err() == nil {
+			log.Printf("Error reloading user cache (%s): %v", reason, err)
+		}
+		return
+	}
+	fmt.Printf("Reloaded user cache (%s): %d users\n", reason, r.Len())
+}
+
+func (r *CachedUserRepository) apply(change domain.UserChange) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	switch change.Op {
+	case domain.UserChangeInsert:
+		r.registered[change.DiscordID] = struct{}{}
+	case domain.UserChangeUpdate:
+		delete(r.registered, change.OldDiscordID)
+		r.registered[change.DiscordID] = struct{}{}
+	case domain.UserChangeDelete:
+		delete(r.registered, change.DiscordID)
+	case domain.UserChangeTruncate:
+		r.registered = make(map[string]struct{})
+	default:
+		log.Printf("Unknown users change op: %q", change.Op)
+	}
+}
@@ -0,0 +1,14 @@
+package repository
+
+import "context"
+
+// ConsentRepository は他のユーザーが自分のなりきりを使うこと（/duelなど）への同意を管理する
+type ConsentRepository interface {
+	SetAllowed(ctx context.Context, discordID string, allowed bool) error
+	// IsAllowed は同意しているか返す。設定がない場合はfalse
+	IsAllowed(ctx context.Context, discordID string) (bool, error)
+	// FindAllowed は登録済みで同意しているユーザーのIDを返す
+	FindAllowed(ctx context.Context) ([]string, error)
+	// FilterAllowed はdiscordIDsのうち登録済みで、同意しているかselfIDの本人であるユーザーのIDを返す
+	FilterAllowed(ctx context.Context, discordIDs []string, selfID string) ([]string, error)
+}
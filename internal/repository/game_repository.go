@@ -0,0 +1,14 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/chun37/doppelcord/internal/domain"
+)
+
+type GameScoreRepository interface {
+	// RecordRound は1ラウンドの結果を加算する。correctは投票したユーザーごとの正解・不正解
+	RecordRound(ctx context.Context, guildID string, correct map[string]bool) error
+	// Leaderboard はポイントの高い順に成績を返す
+	Leaderboard(ctx context.Context, guildID string, limit int) ([]*domain.GameScore, error)
+}
@@ -0,0 +1,23 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/chun37/doppelcord/internal/domain"
+)
+
+type GenerationRepository interface {
+	// Save は生成の記録を保存し、採番されたIDと作成日時を設定する
+	Save(ctx context.Context, gen *domain.Generation) error
+	// SumUsage はsince以降のユーザーのトークン使用量を集計する
+	SumUsage(ctx context.Context, discordID string, since time.Time) (domain.UsageSummary, error)
+	// FindByID は生成の記録を返す。存在しない場合はnil
+	FindByID(ctx context.Context, id int64) (*domain.Generation, error)
+	// FindWellRated は生成した本人が👍を付けた生成を新しい順に返す。他のユーザーの評価は数えない
+	FindWellRated(ctx context.Context, discordID string, limit int) ([]*domain.Generation, error)
+	// SaveFeedback は評価を保存する。同じユーザーの評価が既にあれば上書きする
+	SaveFeedback(ctx context.Context, feedback *domain.GenerationFeedback) error
+	// LastCreatedAt はユーザーの直近の成功した生成の日時を返す。生成がない場合はnil
+	LastCreatedAt(ctx context.Context, discordID string) (*time.Time, error)
+}
@@ -7,8 +7,40 @@ import (
 	"github.com/chun37/doppelcord/internal/domain"
 )
 
+// MessageSearchQuery はメッセージ検索の条件。空の項目は絞り込まない
+type MessageSearchQuery struct {
+	// Text は空白区切りのキーワード。すべてを含むメッセージを返す
+	Text      string
+	DiscordID string
+	ChannelID string
+	// From 以降、To より前に送信されたメッセージに絞り込む
+	From  *time.Time
+	To    *time.Time
+	Limit int
+}
+
+// TimeRange は送信日時の範囲（From以降、Toより前）。ゼロ値の端は絞り込まない
+type TimeRange struct {
+	From time.Time
+	To   time.Time
+}
+
 type MessageRepository interface {
 	Save(ctx context.Context, msg *domain.Message) error
 	FindByDiscordID(ctx context.Context, discordID string, limit int, before *time.Time) ([]*domain.Message, error)
 	FindByDiscordIDAndChannelID(ctx context.Context, discordID, channelID string, limit int) ([]*domain.Message, error)
+	// FindByChannelID はチャンネルのメッセージを投稿者を問わず新しい順に返す
+	FindByChannelID(ctx context.Context, channelID string, limit int) ([]*domain.Message, error)
+	// FindByDiscordIDs はユーザーごとに指定した件数まで、新しい順にメッセージを返す（limitsのキーはユーザーID）
+	FindByDiscordIDs(ctx context.Context, limits map[string]int) ([]*domain.Message, error)
+	// FindByDiscordIDInRange はfrom以降、toより前に送信されたメッセージを新しい順に返す
+	FindByDiscordIDInRange(ctx context.Context, discordID string, from, to time.Time, limit int) ([]*domain.Message, error)
+	// FindReplies はユーザーが他のユーザーに返信したメッセージのうち、返信先の本文があるものを期間内で新しい順に返す
+	FindReplies(ctx context.Context, discordID string, period TimeRange, limit int) ([]*domain.Message, error)
+	// FindRelated は期間内のメッセージをtextと共通する文字bigramが多いものから順に返す。同数の場合は新しい順
+	FindRelated(ctx context.Context, discordID, text string, period TimeRange, limit int) ([]*domain.Message, error)
+	// FindRandom はユーザーがchannelIDsのチャンネルに送信したメッセージから無作為にlimit件を返す
+	FindRandom(ctx context.Context, discordID string, channelIDs []string, limit int) ([]*domain.Message, error)
+	// Search はキーワードを含むメッセージを新しい順に返す
+	Search(ctx context.Context, q MessageSearchQuery) ([]*domain.Message, error)
 }
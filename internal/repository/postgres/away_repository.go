@@ -0,0 +1,64 @@
+package postgres
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/chun37/doppelcord/internal/domain"
+	"github.com/chun37/doppelcord/internal/repository"
+)
+
+type awayRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewAwayRepository(pool *pgxpool.Pool) repository.AwayRepository {
+	return &awayRepository{pool: pool}
+}
+
+func (r *awayRepository) Set(ctx context.Context, status *domain.AwayStatus) error {
+	query := `
+		INSERT INTO away_statuses (discord_id, until, note)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (discord_id)
+		DO UPDATE SET until = EXCLUDED.until, note = EXCLUDED.note, updated_at = CURRENT_TIMESTAMP
+	`
+	_, err := r.pool.Exec(ctx, query, status.DiscordID, status.Until, status.Note)
+	return err
+}
+
+func (r *awayRepository) Clear(ctx context.Context, discordID string) (bool, error) {
+	tag, err := r.pool.Exec(ctx, `DELETE FROM away_statuses WHERE discord_id = $1`, discordID)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+func (r *awayRepository) FindActive(ctx context.Context, discordIDs []string, now time.Time) ([]*domain.AwayStatus, error) {
+	query := `
+		SELECT discord_id, until, note
+		FROM away_statuses
+		WHERE discord_id = ANY($1) AND until > $2
+	`
+	rows, err := r.pool.Query(ctx, query, discordIDs, now)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var statuses []*domain.AwayStatus
+	for rows.Next() {
+		var status domain.AwayStatus
+		if err := rows.Scan(&status.DiscordID, &status.Until, &status.Note); err != nil {
+			return nil, err
+		}
+		statuses = append(statuses, &status)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return statuses, nil
+}
@@ -0,0 +1,82 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/chun37/doppelcord/internal/repository"
+)
+
+type consentRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewConsentRepository(pool *pgxpool.Pool) repository.ConsentRepository {
+	return &consentRepository{pool: pool}
+}
+
+func (r *consentRepository) SetAllowed(ctx context.Context, discordID string, allowed bool) error {
+	query := `
+		INSERT INTO persona_consents (discord_id, allowed)
+		VALUES ($1, $2)
+		ON CONFLICT (discord_id)
+		DO UPDATE SET allowed = EXCLUDED.allowed, updated_at = CURRENT_TIMESTAMP
+	`
+	_, err := r.pool.Exec(ctx, query, discordID, allowed)
+	return err
+}
+
+func (r *consentRepository) IsAllowed(ctx context.Context, discordID string) (bool, error) {
+	query := `SELECT allowed FROM persona_consents WHERE discord_id = $1`
+	var allowed bool
+	err := r.pool.QueryRow(ctx, query, discordID).Scan(&allowed)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+	return allowed, err
+}
+
+func (r *consentRepository) FindAllowed(ctx context.Context) ([]string, error) {
+	query := `
+		SELECT u.discord_id
+		FROM users u
+		JOIN persona_consents c ON c.discord_id = u.discord_id
+		WHERE c.allowed
+	`
+	return r.queryIDs(ctx, query)
+}
+
+func (r *consentRepository) FilterAllowed(ctx context.Context, discordIDs []string, selfID string) ([]string, error) {
+	query := `
+		SELECT u.discord_id
+		FROM users u
+		LEFT JOIN persona_consents c ON c.discord_id = u.discord_id
+		WHERE u.discord_id = ANY($1::text[])
+		  AND (u.discord_id = $2 OR COALESCE(c.allowed, false))
+	`
+	return r.queryIDs(ctx, query, discordIDs, selfID)
+}
+
+func (r *consentRepository) queryIDs(ctx context.Context, query string, args ...any) ([]string, error) {
+	rows, err := r.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
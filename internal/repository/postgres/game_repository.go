@@ -0,0 +1,68 @@
+package postgres
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/chun37/doppelcord/internal/domain"
+	"github.com/chun37/doppelcord/internal/repository"
+)
+
+type gameScoreRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewGameScoreRepository(pool *pgxpool.Pool) repository.GameScoreRepository {
+	return &gameScoreRepository{pool: pool}
+}
+
+func (r *gameScoreRepository) RecordRound(ctx context.Context, guildID string, correct map[string]bool) error {
+	query := `
+		INSERT INTO game_scores (guild_id, discord_id, points, rounds_played)
+		VALUES ($1, $2, $3, 1)
+		ON CONFLICT (guild_id, discord_id)
+		DO UPDATE SET
+			points = game_scores.points + EXCLUDED.points,
+			rounds_played = game_scores.rounds_played + 1,
+			updated_at = CURRENT_TIMESTAMP
+	`
+	batch := &pgx.Batch{}
+	for discordID, ok := range correct {
+		points := 0
+		if ok {
+			points = 1
+		}
+		batch.Queue(query, guildID, discordID, points)
+	}
+	return r.pool.SendBatch(ctx, batch).Close()
+}
+
+func (r *gameScoreRepository) Leaderboard(ctx context.Context, guildID string, limit int) ([]*domain.GameScore, error) {
+	query := `
+		SELECT guild_id, discord_id, points, rounds_played
+		FROM game_scores
+		WHERE guild_id = $1
+		ORDER BY points DESC, rounds_played ASC
+		LIMIT $2
+	`
+	rows, err := r.pool.Query(ctx, query, guildID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var scores []*domain.GameScore
+	for rows.Next() {
+		var score domain.GameScore
+		if err := rows.Scan(&score.GuildID, &score.DiscordID, &score.Points, &score.RoundsPlayed); err != nil {
+			return nil, err
+		}
+		scores = append(scores, &score)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return scores, nil
+}
@@ -0,0 +1,132 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/chun37/doppelcord/internal/domain"
+	"github.com/chun37/doppelcord/internal/repository"
+)
+
+type generationRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewGenerationRepository(pool *pgxpool.Pool) repository.GenerationRepository {
+	return &generationRepository{pool: pool}
+}
+
+const generationColumns = `g.id, g.discord_id, g.guild_id, g.channel_id, g.command, g.model, g.status,
+	g.latency_ms, g.prompt_tokens, g.completion_tokens, g.prompt_hash, g.content, g.created_at`
+
+func (r *generationRepository) Save(ctx context.Context, gen *domain.Generation) error {
+	query := `
+		INSERT INTO generations (
+			discord_id, guild_id, channel_id, command, model, status,
+			latency_ms, prompt_tokens, completion_tokens, prompt_hash, content
+		)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+		RETURNING id, created_at
+	`
+	return r.pool.QueryRow(ctx, query,
+		gen.DiscordID, gen.GuildID, gen.ChannelID, gen.Command, gen.Model, gen.Status,
+		gen.Latency.Milliseconds(), gen.PromptTokens, gen.CompletionTokens, gen.PromptHash, gen.Content,
+	).Scan(&gen.ID, &gen.CreatedAt)
+}
+
+func (r *generationRepository) FindByID(ctx context.Context, id int64) (*domain.Generation, error) {
+	query := `
+		SELECT ` + generationColumns + `
+		FROM generations g
+		WHERE g.id = $1
+	`
+	gen, err := scanGeneration(r.pool.QueryRow(ctx, query, id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	return gen, err
+}
+
+func (r *generationRepository) FindWellRated(ctx context.Context, discordID string, limit int) ([]*domain.Generation, error) {
+	query := `
+		SELECT ` + generationColumns + `
+		FROM generations g
+		JOIN generation_feedback f ON f.generation_id = g.id AND f.discord_id = g.discord_id
+		WHERE g.discord_id = $1 AND g.status = 'success' AND f.rating > 0
+		ORDER BY g.created_at DESC
+		LIMIT $2
+	`
+	rows, err := r.pool.Query(ctx, query, discordID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var generations []*domain.Generation
+	for rows.Next() {
+		gen, err := scanGeneration(rows)
+		if err != nil {
+			return nil, err
+		}
+		generations = append(generations, gen)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return generations, nil
+}
+
+func (r *generationRepository) SaveFeedback(ctx context.Context, feedback *domain.GenerationFeedback) error {
+	query := `
+		INSERT INTO generation_feedback (generation_id, discord_id, rating)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (generation_id, discord_id)
+		DO UPDATE SET rating = EXCLUDED.rating, updated_at = CURRENT_TIMESTAMP
+	`
+	_, err := r.pool.Exec(ctx, query, feedback.GenerationID, feedback.DiscordID, feedback.Rating)
+	return err
+}
+
+func (r *generationRepository) SumUsage(ctx context.Context, discordID string, since time.Time) (domain.UsageSummary, error) {
+	query := `
+		SELECT COUNT(*), COALESCE(SUM(prompt_tokens), 0), COALESCE(SUM(completion_tokens), 0)
+		FROM generations
+		WHERE discord_id = $1 AND created_at >= $2
+	`
+	var summary domain.UsageSummary
+	err := r.pool.QueryRow(ctx, query, discordID, since).Scan(
+		&summary.Generations, &summary.PromptTokens, &summary.CompletionTokens,
+	)
+	return summary, err
+}
+
+func (r *generationRepository) LastCreatedAt(ctx context.Context, discordID string) (*time.Time, error) {
+	query := `
+		SELECT MAX(created_at)
+		FROM generations
+		WHERE discord_id = $1 AND status = 'success'
+	`
+	var last *time.Time
+	if err := r.pool.QueryRow(ctx, query, discordID).Scan(&last); err != nil {
+		return nil, err
+	}
+	return last, nil
+}
+
+func scanGeneration(row pgx.Row) (*domain.Generation, error) {
+	var gen domain.Generation
+	var latencyMS int64
+	err := row.Scan(
+		&gen.ID, &gen.DiscordID, &gen.GuildID, &gen.ChannelID, &gen.Command, &gen.Model, &gen.Status,
+		&latencyMS, &gen.PromptTokens, &gen.CompletionTokens, &gen.PromptHash, &gen.Content, &gen.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	gen.Latency = time.Duration(latencyMS) * time.Millisecond
+	return &gen, nil
+}
@@ -2,12 +2,16 @@ package postgres
 
 import (
 	"context"
+	"fmt"
+	"strings"
 	"time"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 
 	"github.com/chun37/doppelcord/internal/domain"
 	"github.com/chun37/doppelcord/internal/repository"
+	"github.com/chun37/doppelcord/internal/textsearch"
 )
 
 type messageRepository struct {
@@ -18,62 +22,218 @@ func NewMessageRepository(pool *pgxpool.Pool) repository.MessageRepository {
 	return &messageRepository{pool: pool}
 }
 
+const messageColumns = `id, discord_id, channel_id, message_id, content,
+	reply_to_message_id, reply_to_author_id, reply_to_content, thread_parent_id,
+	created_at, stored_at`
+
 func (r *messageRepository) Save(ctx context.Context, msg *domain.Message) error {
 	query := `
-		INSERT INTO messages (discord_id, channel_id, message_id, content, created_at)
-		VALUES ($1, $2, $3, $4, $5)
+		INSERT INTO messages (
+			discord_id, channel_id, message_id, content,
+			reply_to_message_id, reply_to_author_id, reply_to_content, thread_parent_id,
+			created_at
+		)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
 		ON CONFLICT (message_id, created_at) DO NOTHING
 	`
 	_, err := r.pool.Exec(ctx, query,
-		msg.DiscordID, msg.ChannelID, msg.MessageID, msg.Content, msg.CreatedAt,
+		msg.DiscordID, msg.ChannelID, msg.MessageID, msg.Content,
+		msg.ReplyToMessageID, msg.ReplyToAuthorID, msg.ReplyToContent, msg.ThreadParentID,
+		msg.CreatedAt,
 	)
 	return err
 }
 
 func (r *messageRepository) FindByDiscordID(ctx context.Context, discordID string, limit int, before *time.Time) ([]*domain.Message, error) {
 	query := `
-		SELECT id, discord_id, channel_id, message_id, content, created_at, stored_at
+		SELECT ` + messageColumns + `
 		FROM messages
 		WHERE discord_id = $1
 		  AND ($2::timestamptz IS NULL OR created_at < $2)
 		ORDER BY created_at DESC
 		LIMIT $3
 	`
-	rows, err := r.pool.Query(ctx, query, discordID, before, limit)
-	if err != nil {
-		return nil, err
+	return r.query(ctx, query, discordID, before, limit)
+}
+
+func (r *messageRepository) FindByDiscordIDAndChannelID(ctx context.Context, discordID, channelID string, limit int) ([]*domain.Message, error) {
+	query := `
+		SELECT ` + messageColumns + `
+		FROM messages
+		WHERE discord_id = $1 AND channel_id = $2
+		ORDER BY created_at DESC
+		LIMIT $3
+	`
+	return r.query(ctx, query, discordID, channelID, limit)
+}
+
+func (r *messageRepository) FindByChannelID(ctx context.Context, channelID string, limit int) ([]*domain.Message, error) {
+	query := `
+		SELECT ` + messageColumns + `
+		FROM messages
+		WHERE channel_id = $1
+		ORDER BY created_at DESC
+		LIMIT $2
+	`
+	return r.query(ctx, query, channelID, limit)
+}
+
+func (r *messageRepository) FindByDiscordIDs(ctx context.Context, limits map[string]int) ([]*domain.Message, error) {
+	ids := make([]string, 0, len(limits))
+	counts := make([]int32, 0, len(limits))
+	for id, limit := range limits {
+		ids = append(ids, id)
+		counts = append(counts, int32(limit))
 	}
-	defer rows.Close()
+	// ユーザーごとにLATERALで(discord_id, created_at)のインデックスを使って新しい順に取得する
+	query := `
+		SELECT m.id, m.discord_id, m.channel_id, m.message_id, m.content,
+			m.reply_to_message_id, m.reply_to_author_id, m.reply_to_content, m.thread_parent_id,
+			m.created_at, m.stored_at
+		FROM unnest($1::text[], $2::int[]) AS l(discord_id, lim)
+		CROSS JOIN LATERAL (
+			SELECT ` + messageColumns + `
+			FROM messages
+			WHERE discord_id = l.discord_id
+			ORDER BY created_at DESC
+			LIMIT l.lim
+		) m
+		ORDER BY m.discord_id, m.created_at DESC
+	`
+	return r.query(ctx, query, ids, counts)
+}
 
-	var messages []*domain.Message
-	for rows.Next() {
-		var msg domain.Message
-		if err := rows.Scan(
-			&msg.ID, &msg.DiscordID, &msg.ChannelID, &msg.MessageID,
-			&msg.Content, &msg.CreatedAt, &msg.StoredAt,
-		); err != nil {
-			return nil, err
-		}
-		messages = append(messages, &msg)
+// FindByDiscordIDInRange は期間の両端を必須の条件にすることで、
+// 月別パーティションのうち期間に含まれるものだけを走査させる
+func (r *messageRepository) FindByDiscordIDInRange(ctx context.Context, discordID string, from, to time.Time, limit int) ([]*domain.Message, error) {
+	query := `
+		SELECT ` + messageColumns + `
+		FROM messages
+		WHERE discord_id = $1
+		  AND created_at >= $2
+		  AND created_at < $3
+		ORDER BY created_at DESC
+		LIMIT $4
+	`
+	return r.query(ctx, query, discordID, from, to, limit)
+}
+
+func (r *messageRepository) FindReplies(ctx context.Context, discordID string, period repository.TimeRange, limit int) ([]*domain.Message, error) {
+	args := []any{discordID, limit}
+	// 条件は部分インデックス idx_messages_discord_id_replies の述語と揃える
+	query := `
+		SELECT ` + messageColumns + `
+		FROM messages
+		WHERE discord_id = $1
+		  AND reply_to_content <> ''
+		  AND reply_to_author_id <> discord_id` + rangeCondition(period, &args) + `
+		ORDER BY created_at DESC
+		LIMIT $2
+	`
+	return r.query(ctx, query, args...)
+}
+
+func (r *messageRepository) FindRelated(ctx context.Context, discordID, text string, period repository.TimeRange, limit int) ([]*domain.Message, error) {
+	if strings.TrimSpace(text) == "" {
+		return nil, nil
 	}
-	if err := rows.Err(); err != nil {
-		return nil, err
+	args := []any{discordID, text, textsearch.MaxTerms, limit}
+	// 検索語はインデックスと同じmessage_bigramsで分割し（長い文は先頭MaxTerms個まで）、
+	// bigramの重なりでインデックスから候補を絞り込んで、重なったbigramの数でスコアを付ける
+	query := `
+		WITH q AS (
+			SELECT (message_bigrams($2))[1:$3] AS terms
+		)
+		SELECT ` + messageColumns + `
+		FROM (
+			SELECT m.*, cardinality(ARRAY(
+				SELECT unnest(message_bigrams(m.content))
+				INTERSECT
+				SELECT unnest((SELECT terms FROM q))
+			)) AS score
+			FROM messages m
+			WHERE m.discord_id = $1
+			  AND message_bigrams(m.content) && (SELECT terms FROM q)` + rangeCondition(period, &args) + `
+		) scored
+		ORDER BY score DESC, created_at DESC
+		LIMIT $4
+	`
+	return r.query(ctx, query, args...)
+}
+
+// rangeCondition は期間の条件を返し、パラメーターをargsに追加する。指定された端だけを
+// created_atの条件にすることで、月別パーティションのうち期間外のものを走査させない
+func rangeCondition(period repository.TimeRange, args *[]any) string {
+	var cond string
+	if !period.From.IsZero() {
+		*args = append(*args, period.From)
+		cond += fmt.Sprintf(" AND created_at >= $%d", len(*args))
 	}
-	return messages, nil
+	if !period.To.IsZero() {
+		*args = append(*args, period.To)
+		cond += fmt.Sprintf(" AND created_at < $%d", len(*args))
+	}
+	return cond
 }
 
-func (r *messageRepository) FindByDiscordIDAndChannelID(ctx context.Context, discordID, channelID string, limit int) ([]*domain.Message, error) {
+func (r *messageRepository) FindRandom(ctx context.Context, discordID string, channelIDs []string, limit int) ([]*domain.Message, error) {
+	if len(channelIDs) == 0 {
+		return nil, nil
+	}
 	query := `
-		SELECT id, discord_id, channel_id, message_id, content, created_at, stored_at
+		SELECT ` + messageColumns + `
 		FROM messages
-		WHERE discord_id = $1 AND channel_id = $2
-		ORDER BY created_at DESC
+		WHERE discord_id = $1 AND channel_id = ANY($2::text[])
+		ORDER BY random()
 		LIMIT $3
 	`
-	rows, err := r.pool.Query(ctx, query, discordID, channelID, limit)
+	return r.query(ctx, query, discordID, channelIDs, limit)
+}
+
+func (r *messageRepository) Search(ctx context.Context, q repository.MessageSearchQuery) ([]*domain.Message, error) {
+	args := []any{q.DiscordID, q.ChannelID, q.From, q.To, q.Limit}
+	query := `
+		SELECT ` + messageColumns + `
+		FROM messages
+		WHERE ($1 = '' OR discord_id = $1)
+		  AND ($2 = '' OR channel_id = $2)
+		  AND ($3::timestamptz IS NULL OR created_at >= $3)
+		  AND ($4::timestamptz IS NULL OR created_at < $4)` + keywordCondition(q.Text, &args) + `
+		ORDER BY created_at DESC
+		LIMIT $5
+	`
+	return r.query(ctx, query, args...)
+}
+
+// keywordCondition はキーワードをすべて含む条件を返し、パラメーターをargsに追加する。
+// bigramの包含でインデックスから候補を絞り込み、キーワードの部分一致で確定する。
+// 検索語もインデックスと同じmessage_bigramsとlowerで分割・小文字化するため、ロケールや照合順序によらず両側が一致する
+func keywordCondition(text string, args *[]any) string {
+	keywords := textsearch.Keywords(text)
+	if len(keywords) == 0 {
+		return ""
+	}
+	*args = append(*args, strings.Join(keywords, " "), keywords)
+	// 1文字だけの部分は本文ではより長い部分に含まれることがあるため、2文字のbigramだけで絞り込む
+	return fmt.Sprintf(`
+		  AND message_bigrams(content) @> ARRAY(
+			SELECT t FROM unnest(message_bigrams($%[1]d)) AS t WHERE char_length(t) = 2
+		  )
+		  AND NOT EXISTS (
+			SELECT 1 FROM unnest($%[2]d::text[]) AS k(keyword)
+			WHERE strpos(lower(content), lower(k.keyword)) = 0
+		  )`, len(*args)-1, len(*args))
+}
+
+func (r *messageRepository) query(ctx context.Context, query string, args ...any) ([]*domain.Message, error) {
+	rows, err := r.pool.Query(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
+	return scanMessages(rows)
+}
+
+func scanMessages(rows pgx.Rows) ([]*domain.Message, error) {
 	defer rows.Close()
 
 	var messages []*domain.Message
@@ -81,7 +241,8 @@ func (r *messageRepository) FindByDiscordIDAndChannelID(ctx context.Context, dis
 		var msg domain.Message
 		if err := rows.Scan(
 			&msg.ID, &msg.DiscordID, &msg.ChannelID, &msg.MessageID,
-			&msg.Content, &msg.CreatedAt, &msg.StoredAt,
+			&msg.Content, &msg.ReplyToMessageID, &msg.ReplyToAuthorID, &msg.ReplyToContent, &msg.ThreadParentID,
+			&msg.CreatedAt, &msg.StoredAt,
 		); err != nil {
 			return nil, err
 		}
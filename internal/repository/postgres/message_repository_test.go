@@ -0,0 +1,55 @@
+package postgres
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// TestKeywordCondition は本文をインデックス側（message_bigrams）、検索語を検索側（keywordCondition）に通し、
+// 本文に含まれる語が見つかることを確認する。マイグレーション済みのDBが必要なため、
+// TEST_DATABASE_URL が設定されている場合のみ実行する
+func TestKeywordCondition(t *testing.T) {
+	url := os.Getenv("TEST_DATABASE_URL")
+	if url == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+	ctx := context.Background()
+	pool, err := pgxpool.New(ctx, url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pool.Close()
+
+	tests := []struct {
+		name    string
+		content string
+		text    string
+		want    bool
+	}{
+		{name: "ascii case", content: "Hello World", text: "hello", want: true},
+		{name: "full-width", content: "ＡＢＣの新作が出た", text: "ＡＢＣ", want: true},
+		{name: "accented", content: "Élise est là", text: "Élise", want: true},
+		{name: "emoji", content: "今日もお疲れ🍺🍺", text: "お疲れ🍺", want: true},
+		{name: "punctuation", content: "よろしく、お願いします！", text: "く、お", want: true},
+		{name: "single char", content: "ラーメン食べたい", text: "メ", want: true},
+		{name: "all keywords", content: "明日の会議は10時から", text: "会議 10時", want: true},
+		{name: "missing keyword", content: "明日の会議は10時から", text: "会議 11時", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args := []any{tt.content}
+			query := `SELECT EXISTS (SELECT 1 FROM (SELECT $1::text AS content) m WHERE true` +
+				keywordCondition(tt.text, &args) + `)`
+			var got bool
+			if err := pool.QueryRow(ctx, query, args...).Scan(&got); err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("keywordCondition(%q) on %q = %v, want %v", tt.text, tt.content, got, tt.want)
+			}
+		})
+	}
+}
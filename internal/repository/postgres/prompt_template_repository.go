@@ -0,0 +1,98 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/chun37/doppelcord/internal/domain"
+	"github.com/chun37/doppelcord/internal/repository"
+)
+
+type promptTemplateRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewPromptTemplateRepository(pool *pgxpool.Pool) repository.PromptTemplateRepository {
+	return &promptTemplateRepository{pool: pool}
+}
+
+const promptTemplateColumns = `id, scope, scope_id, version, system_template, user_template, created_by, created_at`
+
+func (r *promptTemplateRepository) Save(ctx context.Context, tmpl *domain.PromptTemplate) error {
+	query := `
+		INSERT INTO prompt_templates (scope, scope_id, version, system_template, user_template, created_by)
+		SELECT $1, $2, COALESCE(MAX(version), 0) + 1, $3, $4, $5
+		FROM prompt_templates
+		WHERE scope = $1 AND scope_id = $2
+		RETURNING id, version, created_at
+	`
+	return r.pool.QueryRow(ctx, query,
+		tmpl.Scope, tmpl.ScopeID, tmpl.SystemTemplate, tmpl.UserTemplate, tmpl.CreatedBy,
+	).Scan(&tmpl.ID, &tmpl.Version, &tmpl.CreatedAt)
+}
+
+func (r *promptTemplateRepository) FindLatest(ctx context.Context, scope domain.PromptTemplateScope, scopeID string) (*domain.PromptTemplate, error) {
+	query := `
+		SELECT ` + promptTemplateColumns + `
+		FROM prompt_templates
+		WHERE scope = $1 AND scope_id = $2
+		ORDER BY version DESC
+		LIMIT 1
+	`
+	return scanPromptTemplate(r.pool.QueryRow(ctx, query, scope, scopeID))
+}
+
+func (r *promptTemplateRepository) FindVersion(ctx context.Context, scope domain.PromptTemplateScope, scopeID string, version int) (*domain.PromptTemplate, error) {
+	query := `
+		SELECT ` + promptTemplateColumns + `
+		FROM prompt_templates
+		WHERE scope = $1 AND scope_id = $2 AND version = $3
+	`
+	return scanPromptTemplate(r.pool.QueryRow(ctx, query, scope, scopeID, version))
+}
+
+func (r *promptTemplateRepository) ListVersions(ctx context.Context, scope domain.PromptTemplateScope, scopeID string, limit int) ([]*domain.PromptTemplate, error) {
+	query := `
+		SELECT ` + promptTemplateColumns + `
+		FROM prompt_templates
+		WHERE scope = $1 AND scope_id = $2
+		ORDER BY version DESC
+		LIMIT $3
+	`
+	rows, err := r.pool.Query(ctx, query, scope, scopeID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var templates []*domain.PromptTemplate
+	for rows.Next() {
+		tmpl, err := scanPromptTemplate(rows)
+		if err != nil {
+			return nil, err
+		}
+		templates = append(templates, tmpl)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return templates, nil
+}
+
+func scanPromptTemplate(row pgx.Row) (*domain.PromptTemplate, error) {
+	var tmpl domain.PromptTemplate
+	err := row.Scan(
+		&tmpl.ID, &tmpl.Scope, &tmpl.ScopeID, &tmpl.Version,
+		&tmpl.SystemTemplate, &tmpl.UserTemplate, &tmpl.CreatedBy, &tmpl.CreatedAt,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &tmpl, nil
+}
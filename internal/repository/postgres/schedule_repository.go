@@ -0,0 +1,159 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/chun37/doppelcord/internal/domain"
+	"github.com/chun37/doppelcord/internal/repository"
+)
+
+type scheduleRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewScheduleRepository(pool *pgxpool.Pool) repository.ScheduleRepository {
+	return &scheduleRepository{pool: pool}
+}
+
+const scheduleColumns = `id, discord_id, guild_id, channel_id, kind, cron_spec,
+	min_interval_minutes, max_interval_minutes, topic, ends_at, next_run_at, last_run_at, created_at`
+
+func (r *scheduleRepository) Save(ctx context.Context, schedule *domain.Schedule) error {
+	query := `
+		INSERT INTO schedules (
+			discord_id, guild_id, channel_id, kind, cron_spec,
+			min_interval_minutes, max_interval_minutes, topic, ends_at, next_run_at
+		)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		RETURNING id, created_at
+	`
+	return r.pool.QueryRow(ctx, query,
+		schedule.DiscordID, schedule.GuildID, schedule.ChannelID, schedule.Kind, schedule.CronSpec,
+		int(schedule.MinInterval.Minutes()), int(schedule.MaxInterval.Minutes()),
+		schedule.Topic, schedule.EndsAt, schedule.NextRunAt,
+	).Scan(&schedule.ID, &schedule.CreatedAt)
+}
+
+func (r *scheduleRepository) FindByID(ctx context.Context, id int64) (*domain.Schedule, error) {
+	query := `SELECT ` + scheduleColumns + ` FROM schedules WHERE id = $1`
+	schedule, err := scanSchedule(r.pool.QueryRow(ctx, query, id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	return schedule, err
+}
+
+func (r *scheduleRepository) ListByDiscordID(ctx context.Context, discordID string) ([]*domain.Schedule, error) {
+	query := `
+		SELECT ` + scheduleColumns + `
+		FROM schedules
+		WHERE discord_id = $1
+		ORDER BY id
+	`
+	return r.query(ctx, query, discordID)
+}
+
+func (r *scheduleRepository) Delete(ctx context.Context, id int64, discordID string) (bool, error) {
+	query := `DELETE FROM schedules WHERE id = $1 AND discord_id = $2`
+	tag, err := r.pool.Exec(ctx, query, id, discordID)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// FindDue は他のプロセスが担当するギルドのスケジュールで件数の上限が埋まらないよう、
+// シャードの割り当て（(guild_id >> 22) % shardCount）をSQLで計算して絞り込む
+func (r *scheduleRepository) FindDue(ctx context.Context, before time.Time, shardCount int, shardIDs []int, limit int) ([]*domain.Schedule, error) {
+	ids := make([]int32, len(shardIDs))
+	for i, id := range shardIDs {
+		ids[i] = int32(id)
+	}
+	query := `
+		SELECT ` + scheduleColumns + `
+		FROM schedules
+		WHERE next_run_at <= $1
+		  AND ((guild_id::bigint >> 22) % $2) = ANY($3::int[])
+		ORDER BY next_run_at
+		LIMIT $4
+	`
+	return r.query(ctx, query, before, shardCount, ids, limit)
+}
+
+func (r *scheduleRepository) UpdateRun(ctx context.Context, id int64, lastRunAt *time.Time, nextRunAt time.Time) error {
+	query := `
+		UPDATE schedules
+		SET last_run_at = COALESCE($2, last_run_at), next_run_at = $3
+		WHERE id = $1
+	`
+	_, err := r.pool.Exec(ctx, query, id, lastRunAt, nextRunAt)
+	return err
+}
+
+// TryLock はセッション単位のアドバイザリロックを取得し、解放まで接続を保持する。
+// プロセスが落ちた場合は接続の切断とともにロックも解放される
+func (r *scheduleRepository) TryLock(ctx context.Context, id int64) (func(), bool, error) {
+	conn, err := r.pool.Acquire(ctx)
+	if err != nil {
+		return nil, false, err
+	}
+
+	var locked bool
+	err = conn.QueryRow(ctx, `SELECT pg_try_advisory_lock(hashtextextended('schedules:' || $1::text, 0))`, id).Scan(&locked)
+	if err != nil || !locked {
+		conn.Release()
+		return nil, false, err
+	}
+
+	unlock := func() {
+		defer conn.Release()
+		// 呼び出し元のコンテキストがキャンセルされていても確実に解放する
+		_, err := conn.Exec(context.Background(), `SELECT pg_advisory_unlock(hashtextextended('schedules:' || $1::text, 0))`, id)
+		if err != nil {
+			log.Printf("Error releasing schedule lock %d: %v", id, err)
+		}
+	}
+	return unlock, true, nil
+}
+
+func (r *scheduleRepository) query(ctx context.Context, query string, args ...any) ([]*domain.Schedule, error) {
+	rows, err := r.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var schedules []*domain.Schedule
+	for rows.Next() {
+		schedule, err := scanSchedule(rows)
+		if err != nil {
+			return nil, err
+		}
+		schedules = append(schedules, schedule)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return schedules, nil
+}
+
+func scanSchedule(row pgx.Row) (*domain.Schedule, error) {
+	var schedule domain.Schedule
+	var minMinutes, maxMinutes int
+	err := row.Scan(
+		&schedule.ID, &schedule.DiscordID, &schedule.GuildID, &schedule.ChannelID, &schedule.Kind, &schedule.CronSpec,
+		&minMinutes, &maxMinutes, &schedule.Topic, &schedule.EndsAt, &schedule.NextRunAt, &schedule.LastRunAt, &schedule.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	schedule.MinInterval = time.Duration(minMinutes) * time.Minute
+	schedule.MaxInterval = time.Duration(maxMinutes) * time.Minute
+	return &schedule, nil
+}
@@ -0,0 +1,185 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/chun37/doppelcord/internal/domain"
+	"github.com/chun37/doppelcord/internal/repository"
+)
+
+const (
+	statsTopChannels = 5
+	statsTopTerms    = 10
+
+	// statsWordPattern は形態素解析なしで語句とみなす文字の並び（漢字・カタカナ・英数字の連続）
+	statsWordPattern = `([一-龠々〆]{2,}|[ァ-ヴー]{2,}|[a-zA-Z][a-zA-Z0-9]{2,})`
+	// statsEmojiPattern はカスタム絵文字と主なUnicode絵文字
+	statsEmojiPattern = `(<a?:\w+:\d+>|[\U0001F300-\U0001FAFF☀-➿])`
+)
+
+type statsRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewStatsRepository(pool *pgxpool.Pool) repository.StatsRepository {
+	return &statsRepository{pool: pool}
+}
+
+func (r *statsRepository) UserStats(ctx context.Context, discordID string, loc *time.Location, since time.Time) (*domain.UserStats, error) {
+	stats := &domain.UserStats{}
+	tz := loc.String()
+
+	steps := []struct {
+		name string
+		fn   func() error
+	}{
+		{"summary", func() error { return r.summary(ctx, discordID, stats) }},
+		{"monthly", func() error { return r.monthly(ctx, discordID, tz, since, stats) }},
+		{"channels", func() error { return r.channels(ctx, discordID, stats) }},
+		{"hours", func() error { return r.hours(ctx, discordID, tz, stats) }},
+		{"words", func() error { return r.terms(ctx, discordID, statsWordPattern, &stats.TopWords) }},
+		{"emoji", func() error { return r.terms(ctx, discordID, statsEmojiPattern, &stats.TopEmoji) }},
+		{"streaks", func() error { return r.streaks(ctx, discordID, tz, loc, stats) }},
+	}
+	for _, step := range steps {
+		if err := step.fn(); err != nil {
+			return nil, fmt.Errorf("failed to aggregate %s: %w", step.name, err)
+		}
+	}
+	return stats, nil
+}
+
+func (r *statsRepository) summary(ctx context.Context, discordID string, stats *domain.UserStats) error {
+	query := `
+		SELECT COUNT(*), COALESCE(AVG(char_length(content)), 0), MIN(created_at)
+		FROM messages
+		WHERE discord_id = $1
+	`
+	return r.pool.QueryRow(ctx, query, discordID).Scan(
+		&stats.TotalMessages, &stats.AverageLength, &stats.FirstMessageAt,
+	)
+}
+
+func (r *statsRepository) monthly(ctx context.Context, discordID, tz string, since time.Time, stats *domain.UserStats) error {
+	// created_atでの絞り込みにより対象期間のパーティションのみを走査する
+	query := `
+		SELECT date_trunc('month', created_at AT TIME ZONE $2) AS month, COUNT(*)
+		FROM messages
+		WHERE discord_id = $1 AND created_at >= $3
+		GROUP BY month
+		ORDER BY month
+	`
+	rows, err := r.pool.Query(ctx, query, discordID, tz, since)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var pc domain.PeriodCount
+		if err := rows.Scan(&pc.Period, &pc.Count); err != nil {
+			return err
+		}
+		stats.Monthly = append(stats.Monthly, pc)
+	}
+	return rows.Err()
+}
+
+func (r *statsRepository) channels(ctx context.Context, discordID string, stats *domain.UserStats) error {
+	query := `
+		SELECT channel_id, COUNT(*) AS count
+		FROM messages
+		WHERE discord_id = $1
+		GROUP BY channel_id
+		ORDER BY count DESC
+		LIMIT $2
+	`
+	rows, err := r.pool.Query(ctx, query, discordID, statsTopChannels)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var cc domain.ChannelCount
+		if err := rows.Scan(&cc.ChannelID, &cc.Count); err != nil {
+			return err
+		}
+		stats.Channels = append(stats.Channels, cc)
+	}
+	return rows.Err()
+}
+
+func (r *statsRepository) hours(ctx context.Context, discordID, tz string, stats *domain.UserStats) error {
+	query := `
+		SELECT EXTRACT(HOUR FROM created_at AT TIME ZONE $2)::int AS hour, COUNT(*)
+		FROM messages
+		WHERE discord_id = $1
+		GROUP BY hour
+	`
+	rows, err := r.pool.Query(ctx, query, discordID, tz)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var hour int
+		var count int64
+		if err := rows.Scan(&hour, &count); err != nil {
+			return err
+		}
+		if hour >= 0 && hour < len(stats.Hours) {
+			stats.Hours[hour] = count
+		}
+	}
+	return rows.Err()
+}
+
+func (r *statsRepository) terms(ctx context.Context, discordID, pattern string, dst *[]domain.TermCount) error {
+	query := `
+		SELECT lower(match[1]) AS term, COUNT(*) AS count
+		FROM messages, regexp_matches(content, $2, 'g') AS match
+		WHERE discord_id = $1
+		GROUP BY term
+		ORDER BY count DESC, term
+		LIMIT $3
+	`
+	rows, err := r.pool.Query(ctx, query, discordID, pattern, statsTopTerms)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var tc domain.TermCount
+		if err := rows.Scan(&tc.Term, &tc.Count); err != nil {
+			return err
+		}
+		*dst = append(*dst, tc)
+	}
+	return rows.Err()
+}
+
+func (r *statsRepository) streaks(ctx context.Context, discordID, tz string, loc *time.Location, stats *domain.UserStats) error {
+	// 連続した日付は「日付 - 連番」が同じ値になることを使ってグループ化する
+	query := `
+		WITH days AS (
+			SELECT DISTINCT (created_at AT TIME ZONE $2)::date AS day
+			FROM messages
+			WHERE discord_id = $1
+		), runs AS (
+			SELECT COUNT(*) AS length, MAX(day) AS last_day
+			FROM (SELECT day, day - (ROW_NUMBER() OVER (ORDER BY day))::int AS grp FROM days) numbered
+			GROUP BY grp
+		)
+		SELECT COALESCE(MAX(length), 0), COALESCE(MAX(length) FILTER (WHERE last_day >= $3::date - 1), 0)
+		FROM runs
+	`
+	today := time.Now().In(loc).Format("2006-01-02")
+	return r.pool.QueryRow(ctx, query, discordID, tz, today).Scan(&stats.LongestStreak, &stats.CurrentStreak)
+}
@@ -0,0 +1,49 @@
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/chun37/doppelcord/internal/domain"
+	"github.com/chun37/doppelcord/internal/repository"
+)
+
+const usersChangedChannel = "users_changed"
+
+type userChangeListener struct {
+	pool *pgxpool.Pool
+}
+
+func NewUserChangeListener(pool *pgxpool.Pool) repository.UserChangeListener {
+	return &userChangeListener{pool: pool}
+}
+
+func (l *userChangeListener) Listen(ctx context.Context, onReady func(), onChange func(domain.UserChange)) error {
+	conn, err := l.pool.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	if _, err := conn.Exec(ctx, "LISTEN "+usersChangedChannel); err != nil {
+		return err
+	}
+	onReady()
+
+	for {
+		n, err := conn.Conn().WaitForNotification(ctx)
+		if err != nil {
+			return err
+		}
+
+		var change domain.UserChange
+		if err := json.Unmarshal([]byte(n.Payload), &change); err != nil {
+			log.Printf("Error decoding users change notification %q: %v", n.Payload, err)
+			continue
+		}
+		onChange(change)
+	}
+}
@@ -0,0 +1,18 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/chun37/doppelcord/internal/domain"
+)
+
+type PromptTemplateRepository interface {
+	// Save は新しいバージョンとしてテンプレートを保存し、採番されたバージョンを設定する
+	Save(ctx context.Context, tmpl *domain.PromptTemplate) error
+	// FindLatest は最新バージョンを返す。存在しない場合はnil
+	FindLatest(ctx context.Context, scope domain.PromptTemplateScope, scopeID string) (*domain.PromptTemplate, error)
+	// FindVersion は指定バージョンを返す。存在しない場合はnil
+	FindVersion(ctx context.Context, scope domain.PromptTemplateScope, scopeID string, version int) (*domain.PromptTemplate, error)
+	// ListVersions は新しい順にバージョンを返す
+	ListVersions(ctx context.Context, scope domain.PromptTemplateScope, scopeID string, limit int) ([]*domain.PromptTemplate, error)
+}
@@ -0,0 +1,27 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/chun37/doppelcord/internal/domain"
+)
+
+// ScheduleRepository はなりきりの定期投稿のスケジュールを管理する
+type ScheduleRepository interface {
+	// Save はスケジュールを作成し、IDと作成日時を設定する
+	Save(ctx context.Context, schedule *domain.Schedule) error
+	// FindByID はスケジュールを返す。存在しない場合はnil
+	FindByID(ctx context.Context, id int64) (*domain.Schedule, error)
+	ListByDiscordID(ctx context.Context, discordID string) ([]*domain.Schedule, error)
+	// Delete はユーザーのスケジュールを削除する。該当がなければfalse
+	Delete(ctx context.Context, id int64, discordID string) (bool, error)
+	// FindDue は実行日時がbefore以前のスケジュールのうち、ギルドがshardIDsのシャード（全体でshardCount個）に
+	// 属するものを実行日時の古い順に返す
+	FindDue(ctx context.Context, before time.Time, shardCount int, shardIDs []int, limit int) ([]*domain.Schedule, error)
+	// UpdateRun は前回・次回の実行日時を更新する。lastRunAtがnilの場合は前回の実行日時を変えない
+	UpdateRun(ctx context.Context, id int64, lastRunAt *time.Time, nextRunAt time.Time) error
+	// TryLock はスケジュールのアドバイザリロックを取得する。他のレプリカが保持している場合はokがfalse。
+	// 取得できた場合は実行後にunlockを呼ぶ
+	TryLock(ctx context.Context, id int64) (unlock func(), ok bool, err error)
+}
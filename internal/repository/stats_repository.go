@@ -0,0 +1,13 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/chun37/doppelcord/internal/domain"
+)
+
+type StatsRepository interface {
+	// UserStats はユーザーの発言の統計を集計する。日付・時刻はlocで判定し、月別の件数はsince以降を集計する
+	UserStats(ctx context.Context, discordID string, loc *time.Location, since time.Time) (*domain.UserStats, error)
+}
@@ -11,3 +11,10 @@ type UserRepository interface {
 	Register(ctx context.Context, discordID string) (*domain.User, error)
 	GetAllDiscordIDs(ctx context.Context) ([]string, error)
 }
+
+// UserChangeListener はusersテーブルの変更通知を購読する
+type UserChangeListener interface {
+	// Listen は購読を開始し、購読が有効になった時点でonReadyを呼び出す。
+	// 以降は変更ごとにonChangeを呼び出し、接続が切れるかctxがキャンセルされるまでブロックする
+	Listen(ctx context.Context, onReady func(), onChange func(domain.UserChange)) error
+}
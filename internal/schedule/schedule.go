@@ -0,0 +1,69 @@
+package schedule
+
+import (
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"time"
+
+	"github.com/robfig/cron/v3"
+
+	"github.com/chun37/doppelcord/internal/domain"
+)
+
+// MinInterval は投稿の最小間隔。これより頻繁に実行されるスケジュールは作成できない
+const MinInterval = 30 * time.Minute
+
+// cronCheckRuns は間隔の検証で確認する実行回数
+const cronCheckRuns = 20
+
+// ErrNeverRuns はcron形式が一致する日時を持たないことを示す
+var ErrNeverRuns = errors.New("schedule never runs")
+
+// ValidateCron は5フィールドのcron形式（@dailyなどの記述子も可）を解析し、
+// 直近の実行がMinInterval以上の間隔になっているか確認する
+func ValidateCron(spec string, loc *time.Location) error {
+	sched, err := cron.ParseStandard(spec)
+	if err != nil {
+		return err
+	}
+
+	prev := sched.Next(time.Now().In(loc))
+	if prev.IsZero() {
+		return ErrNeverRuns
+	}
+	for range cronCheckRuns {
+		next := sched.Next(prev)
+		if next.IsZero() {
+			break
+		}
+		if next.Sub(prev) < MinInterval {
+			return fmt.Errorf("runs more often than every %s", MinInterval)
+		}
+		prev = next
+	}
+	return nil
+}
+
+// Next はafterより後の次回実行日時を返す。cron形式はlocのタイムゾーンで解釈する
+func Next(s *domain.Schedule, after time.Time, loc *time.Location) (time.Time, error) {
+	switch s.Kind {
+	case domain.ScheduleKindCron:
+		sched, err := cron.ParseStandard(s.CronSpec)
+		if err != nil {
+			return time.Time{}, err
+		}
+		next := sched.Next(after.In(loc))
+		if next.IsZero() {
+			return time.Time{}, ErrNeverRuns
+		}
+		return next, nil
+	case domain.ScheduleKindRandom:
+		interval := s.MinInterval
+		if spread := s.MaxInterval - s.MinInterval; spread > 0 {
+			interval += rand.N(spread)
+		}
+		return after.Add(interval), nil
+	}
+	return time.Time{}, fmt.Errorf("unknown schedule kind %q", s.Kind)
+}
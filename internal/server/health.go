@@ -0,0 +1,99 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/chun37/doppelcord/internal/metrics"
+)
+
+const checkTimeout = 5 * time.Second
+
+// Check はレディネス判定に使う依存先のチェック関数
+type Check func(ctx context.Context) error
+
+// HealthServer は/healthz, /readyz, /metricsを公開するHTTPサーバー
+type HealthServer struct {
+	server *http.Server
+	checks map[string]Check
+}
+
+// NewHealthServer は新しいHealthServerを生成
+func NewHealthServer(addr string, registry *metrics.Registry, checks map[string]Check) *HealthServer {
+	s := &HealthServer{checks: checks}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", s.handleHealthz)
+	mux.HandleFunc("/readyz", s.handleReadyz)
+	mux.Handle("/metrics", registry.Handler())
+
+	s.server = &http.Server{
+		Addr:              addr,
+		Handler:           mux,
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+	return s
+}
+
+// Start はバックグラウンドでリッスンを開始する
+func (s *HealthServer) Start() error {
+	ln, err := net.Listen("tcp", s.server.Addr)
+	if err != nil {
+		return err
+	}
+
+	go func() {
+		if err := s.server.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Printf("Health server error: %v", err)
+		}
+	}()
+	return nil
+}
+
+// Shutdown はサーバーを停止する
+func (s *HealthServer) Shutdown(ctx context.Context) error {
+	return s.server.Shutdown(ctx)
+}
+
+func (s *HealthServer) handleHealthz(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Write([]byte("ok\n"))
+}
+
+type readyResponse struct {
+	Status string            `json:"status"`
+	Checks map[string]string `json:"checks"`
+}
+
+func (s *HealthServer) handleReadyz(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), checkTimeout)
+	defer cancel()
+
+	names := make([]string, 0, len(s.checks))
+	for name := range s.checks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	resp := readyResponse{Status: "ok", Checks: make(map[string]string, len(names))}
+	for _, name := range names {
+		if err := s.checks[name](ctx); err != nil {
+			resp.Status = "unavailable"
+			resp.Checks[name] = err.Error()
+			continue
+		}
+		resp.Checks[name] = "ok"
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if resp.Status != "ok" {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	json.NewEncoder(w).Encode(resp)
+}
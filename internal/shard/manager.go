@@ -0,0 +1,275 @@
+package shard
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"maps"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// Config はシャーディングの設定
+type Config struct {
+	Token   string
+	Intents discordgo.Intent
+	// Count は全体のシャード数。0の場合は/gateway/botの推奨値を使う
+	Count int
+	// IDs はこのプロセスが担当するシャードID。空の場合は全シャードを担当する
+	IDs []int
+}
+
+// Status はシャードの接続状態
+type Status struct {
+	ID        int
+	Count     int
+	Connected bool
+	Latency   time.Duration
+}
+
+// Manager はこのプロセスが担当するシャードごとのDiscordセッションを管理する
+type Manager struct {
+	count    int
+	sessions []*discordgo.Session
+
+	mu        sync.RWMutex
+	connected map[int]bool
+}
+
+// NewManager はシャードごとのセッションを生成する（接続はOpenで行う）
+func NewManager(cfg Config) (*Manager, error) {
+	count := cfg.Count
+	if count == 0 {
+		recommended, err := recommendedShardCount(cfg.Token)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get recommended shard count: %w", err)
+		}
+		count = recommended
+	}
+
+	ids := cfg.IDs
+	if len(ids) == 0 {
+		ids = make([]int, count)
+		for i := range ids {
+			ids[i] = i
+		}
+	}
+
+	m := &Manager{count: count, connected: make(map[int]bool, len(ids))}
+	for _, id := range ids {
+		if id < 0 || id >= count {
+			return nil, fmt.Errorf("shard ID %d is out of range for shard count %d", id, count)
+		}
+
+		dg, err := discordgo.New("Bot " + cfg.Token)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create session for shard %d: %w", id, err)
+		}
+		dg.ShardID = id
+		dg.ShardCount = count
+		dg.Identify.Intents = cfg.Intents
+
+		dg.AddHandler(m.onConnect)
+		dg.AddHandler(m.onDisconnect)
+		dg.AddHandler(m.onReady)
+		dg.AddHandler(m.onResumed)
+
+		m.sessions = append(m.sessions, dg)
+	}
+	return m, nil
+}
+
+func recommendedShardCount(token string) (int, error) {
+	dg, err := discordgo.New("Bot " + token)
+	if err != nil {
+		return 0, err
+	}
+	gw, err := dg.GatewayBot()
+	if err != nil {
+		return 0, err
+	}
+	if gw.Shards < 1 {
+		return 1, nil
+	}
+	return gw.Shards, nil
+}
+
+// Count は全体のシャード数を返す
+func (m *Manager) Count() int {
+	return m.count
+}
+
+// IDs はこのプロセスが担当するシャードIDを返す
+func (m *Manager) IDs() []int {
+	ids := make([]int, 0, len(m.sessions))
+	for _, dg := range m.sessions {
+		ids = append(ids, dg.ShardID)
+	}
+	return ids
+}
+
+// Sessions はこのプロセスが担当するセッションを返す
+func (m *Manager) Sessions() []*discordgo.Session {
+	return m.sessions
+}
+
+// AddHandler は全セッションにハンドラーを登録し、解除用の関数を返す
+func (m *Manager) AddHandler(handler interface{}) func() {
+	removers := make([]func(), 0, len(m.sessions))
+	for _, dg := range m.sessions {
+		removers = append(removers, dg.AddHandler(handler))
+	}
+	return func() {
+		for _, remove := range removers {
+			remove()
+		}
+	}
+}
+
+// identifyInterval はレートリミットのバケットごとにIdentifyを送る間隔
+const identifyInterval = 5 * time.Second
+
+// Open は全シャードの接続を開く。Identifyのレートリミットに従い、
+// max_concurrency個のシャードずつ、バケットの間にidentifyIntervalを空けて接続する
+func (m *Manager) Open() error {
+	if len(m.sessions) == 0 {
+		return nil
+	}
+
+	maxConcurrency := 1
+	gw, err := m.sessions[0].GatewayBot()
+	if err != nil {
+		log.Printf("Failed to get session start limit, identifying one shard at a time: %v", err)
+	} else {
+		limit := gw.SessionStartLimit
+		log.Printf("Session start limit: %d/%d remaining (resets in %s), max concurrency %d",
+			limit.Remaining, limit.Total, time.Duration(limit.ResetAfter)*time.Millisecond, limit.MaxConcurrency)
+		if limit.Remaining < len(m.sessions) {
+			log.Printf("Only %d session starts remain for %d shards; some shards may fail to connect", limit.Remaining, len(m.sessions))
+		}
+		if limit.MaxConcurrency > 1 {
+			maxConcurrency = limit.MaxConcurrency
+		}
+	}
+
+	for n, bucket := range identifyBuckets(m.sessions, maxConcurrency) {
+		if n > 0 {
+			time.Sleep(identifyInterval)
+		}
+		if err := openAll(bucket); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// identifyBuckets はシャードIDをmaxConcurrencyで割った値ごとにセッションをまとめ、ID順に返す。
+// 同じバケットのシャードはレートリミットのキー（shard_id % max_concurrency）が異なるため同時に接続できる
+func identifyBuckets(sessions []*discordgo.Session, maxConcurrency int) [][]*discordgo.Session {
+	byBucket := make(map[int][]*discordgo.Session)
+	for _, dg := range sessions {
+		key := dg.ShardID / maxConcurrency
+		byBucket[key] = append(byBucket[key], dg)
+	}
+	keys := slices.Sorted(maps.Keys(byBucket))
+	buckets := make([][]*discordgo.Session, 0, len(keys))
+	for _, key := range keys {
+		buckets = append(buckets, byBucket[key])
+	}
+	return buckets
+}
+
+// openAll はセッションの接続を並行して開く
+func openAll(sessions []*discordgo.Session) error {
+	errs := make([]error, len(sessions))
+	var wg sync.WaitGroup
+	for n, dg := range sessions {
+		wg.Go(func() {
+			if err := dg.Open(); err != nil {
+				errs[n] = fmt.Errorf("shard %d: %w", dg.ShardID, err)
+			}
+		})
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+// Close は全シャードの接続を閉じる
+func (m *Manager) Close() error {
+	var errs []error
+	for _, dg := range m.sessions {
+		if err := dg.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("shard %d: %w", dg.ShardID, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// ShardForGuild はギルドを担当するシャードIDを返す
+func ShardForGuild(guildID string, count int) (int, error) {
+	id, err := strconv.ParseUint(guildID, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid guild ID %q: %w", guildID, err)
+	}
+	return int((id >> 22) % uint64(count)), nil
+}
+
+// SessionForGuild はギルドを担当するセッションを返す。このプロセスが担当していない場合はnil
+func (m *Manager) SessionForGuild(guildID string) *discordgo.Session {
+	shardID, err := ShardForGuild(guildID, m.count)
+	if err != nil {
+		return nil
+	}
+	for _, dg := range m.sessions {
+		if dg.ShardID == shardID {
+			return dg
+		}
+	}
+	return nil
+}
+
+// Statuses は各シャードの接続状態を返す
+func (m *Manager) Statuses() []Status {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	statuses := make([]Status, 0, len(m.sessions))
+	for _, dg := range m.sessions {
+		statuses = append(statuses, Status{
+			ID:        dg.ShardID,
+			Count:     m.count,
+			Connected: m.connected[dg.ShardID],
+			Latency:   dg.HeartbeatLatency(),
+		})
+	}
+	return statuses
+}
+
+func (m *Manager) setConnected(s *discordgo.Session, connected bool) {
+	m.mu.Lock()
+	m.connected[s.ShardID] = connected
+	m.mu.Unlock()
+}
+
+func (m *Manager) onConnect(s *discordgo.Session, _ *discordgo.Connect) {
+	log.Printf("[Shard %d/%d] Connected to gateway", s.ShardID, s.ShardCount)
+}
+
+func (m *Manager) onReady(s *discordgo.Session, r *discordgo.Ready) {
+	m.setConnected(s, true)
+	log.Printf("[Shard %d/%d] Ready (%d guilds)", s.ShardID, s.ShardCount, len(r.Guilds))
+}
+
+func (m *Manager) onResumed(s *discordgo.Session, _ *discordgo.Resumed) {
+	m.setConnected(s, true)
+	log.Printf("[Shard %d/%d] Resumed", s.ShardID, s.ShardCount)
+}
+
+func (m *Manager) onDisconnect(s *discordgo.Session, _ *discordgo.Disconnect) {
+	m.setConnected(s, false)
+	log.Printf("[Shard %d/%d] Disconnected from gateway", s.ShardID, s.ShardCount)
+}
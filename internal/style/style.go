@@ -0,0 +1,132 @@
+package style
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Profile は発言群の文体の特徴
+type Profile struct {
+	MeanLength float64
+	// EmojiRate は1発言あたりの絵文字の数
+	EmojiRate float64
+	// Endings はよく使う文末表現（多い順）
+	Endings []string
+	// Punctuation はよく使う記号（多い順）
+	Punctuation []string
+}
+
+// ProfileOf は発言群の文体の特徴を集計する。文末表現と記号は上位n件まで
+func ProfileOf(texts []string, n int) Profile {
+	mean := 0.0
+	if ls := Lengths(texts); len(ls) > 0 {
+		for _, l := range ls {
+			mean += l
+		}
+		mean /= float64(len(ls))
+	}
+	return Profile{
+		MeanLength:  mean,
+		EmojiRate:   EmojiRate(texts),
+		Endings:     Top(EndingCounts(texts), n),
+		Punctuation: Top(PunctuationCounts(texts), n),
+	}
+}
+
+var customEmojiPattern = regexp.MustCompile(`<a?:\w+:\d+>`)
+
+// CountEmoji はUnicode絵文字とDiscordのカスタム絵文字の数を返す
+func CountEmoji(text string) int {
+	count := len(customEmojiPattern.FindAllStringIndex(text, -1))
+	for _, r := range customEmojiPattern.ReplaceAllString(text, "") {
+		if isEmoji(r) {
+			count++
+		}
+	}
+	return count
+}
+
+func isEmoji(r rune) bool {
+	switch {
+	case r >= 0x1F300 && r <= 0x1FAFF:
+		return true
+	case r >= 0x2600 && r <= 0x27BF:
+		return true
+	case r >= 0x1F000 && r <= 0x1F2FF:
+		return true
+	}
+	return false
+}
+
+// EmojiRate は1発言あたりの絵文字の数を返す
+func EmojiRate(texts []string) float64 {
+	if len(texts) == 0 {
+		return 0
+	}
+	total := 0
+	for _, text := range texts {
+		total += CountEmoji(text)
+	}
+	return float64(total) / float64(len(texts))
+}
+
+// Lengths は各発言の文字数を昇順で返す
+func Lengths(texts []string) []float64 {
+	ls := make([]float64, len(texts))
+	for i, text := range texts {
+		ls[i] = float64(utf8.RuneCountInString(text))
+	}
+	sort.Float64s(ls)
+	return ls
+}
+
+// PunctuationCounts は句読点・記号ごとの出現数を返す
+func PunctuationCounts(texts []string) map[string]int {
+	counts := make(map[string]int)
+	for _, text := range texts {
+		for _, r := range text {
+			if unicode.IsPunct(r) || r == '〜' || r == '～' || r == 'ー' {
+				counts[string(r)]++
+			}
+		}
+	}
+	return counts
+}
+
+// EndingCounts は各メッセージの末尾2文字（空白と絵文字を除く）の出現数を返す
+func EndingCounts(texts []string) map[string]int {
+	counts := make(map[string]int)
+	for _, text := range texts {
+		text = customEmojiPattern.ReplaceAllString(text, "")
+		runes := []rune(strings.TrimRightFunc(text, func(r rune) bool {
+			return unicode.IsSpace(r) || isEmoji(r) || r == 0xFE0F || r == 0x200D
+		}))
+		if len(runes) == 0 {
+			continue
+		}
+		start := max(len(runes)-2, 0)
+		counts[string(runes[start:])]++
+	}
+	return counts
+}
+
+// Top は出現数の多い順に最大n件のキーを返す。同数の場合はキーの順
+func Top(counts map[string]int, n int) []string {
+	keys := make([]string, 0, len(counts))
+	for k := range counts {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if counts[keys[i]] != counts[keys[j]] {
+			return counts[keys[i]] > counts[keys[j]]
+		}
+		return keys[i] < keys[j]
+	})
+	if len(keys) > n {
+		keys = keys[:n]
+	}
+	return keys
+}
@@ -0,0 +1,15 @@
+package textsearch
+
+import "strings"
+
+// MaxTerms は1回の検索で使うキーワード・bigramの最大数
+const MaxTerms = 32
+
+// Keywords は空白で区切った検索キーワードを返す。大文字・小文字はインデックスと揃えるためSQLのlowerで区別しない
+func Keywords(text string) []string {
+	fields := strings.Fields(text)
+	if len(fields) > MaxTerms {
+		fields = fields[:MaxTerms]
+	}
+	return fields
+}
@@ -0,0 +1,123 @@
+package usage
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/chun37/doppelcord/internal/domain"
+	"github.com/chun37/doppelcord/internal/repository"
+)
+
+// Limits はユーザーごとの利用制限。0の項目は無制限
+type Limits struct {
+	DailyTokens   int64
+	MonthlyTokens int64
+	Cooldown      time.Duration
+	// Location は日・月の区切りの判定に使うタイムゾーン
+	Location *time.Location
+}
+
+// LimitReason は制限に達した理由
+type LimitReason string
+
+const (
+	LimitReasonCooldown LimitReason = "cooldown"
+	LimitReasonDaily    LimitReason = "daily"
+	LimitReasonMonthly  LimitReason = "monthly"
+)
+
+// LimitError は利用制限に達した場合のエラー
+type LimitError struct {
+	Reason LimitReason
+	// RetryAt は再び利用できるようになる日時
+	RetryAt time.Time
+}
+
+func (e *LimitError) Error() string {
+	return fmt.Sprintf("usage limit exceeded (%s) until %s", e.Reason, e.RetryAt.Format(time.RFC3339))
+}
+
+// Report はユーザーの利用状況
+type Report struct {
+	Today     domain.UsageSummary
+	ThisMonth domain.UsageSummary
+	Limits    Limits
+	// CooldownUntil はクールダウンが明ける日時。クールダウン中でなければゼロ値
+	CooldownUntil time.Time
+}
+
+// Service はトークン使用量の記録と利用制限の判定を行う
+type Service struct {
+	repo   repository.GenerationRepository
+	limits Limits
+	now    func() time.Time
+}
+
+func NewService(repo repository.GenerationRepository, limits Limits) *Service {
+	if limits.Location == nil {
+		limits.Location = time.Local
+	}
+	return &Service{repo: repo, limits: limits, now: time.Now}
+}
+
+// Check はユーザーが生成を実行できるか判定し、制限に達している場合は*LimitErrorを返す
+func (s *Service) Check(ctx context.Context, discordID string) error {
+	report, err := s.Report(ctx, discordID)
+	if err != nil {
+		return err
+	}
+
+	if !report.CooldownUntil.IsZero() {
+		return &LimitError{Reason: LimitReasonCooldown, RetryAt: report.CooldownUntil}
+	}
+	dayStart, monthStart := s.periodStarts()
+	if s.limits.MonthlyTokens > 0 && report.ThisMonth.TotalTokens() >= s.limits.MonthlyTokens {
+		return &LimitError{Reason: LimitReasonMonthly, RetryAt: monthStart.AddDate(0, 1, 0)}
+	}
+	if s.limits.DailyTokens > 0 && report.Today.TotalTokens() >= s.limits.DailyTokens {
+		return &LimitError{Reason: LimitReasonDaily, RetryAt: dayStart.AddDate(0, 0, 1)}
+	}
+	return nil
+}
+
+// Record は生成の記録を保存する
+func (s *Service) Record(ctx context.Context, gen *domain.Generation) error {
+	return s.repo.Save(ctx, gen)
+}
+
+// Report はユーザーの今日・今月の使用量とクールダウンの状態を返す
+func (s *Service) Report(ctx context.Context, discordID string) (*Report, error) {
+	dayStart, monthStart := s.periodStarts()
+
+	month, err := s.repo.SumUsage(ctx, discordID, monthStart)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sum monthly usage: %w", err)
+	}
+	today, err := s.repo.SumUsage(ctx, discordID, dayStart)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sum daily usage: %w", err)
+	}
+
+	report := &Report{Today: today, ThisMonth: month, Limits: s.limits}
+	if s.limits.Cooldown > 0 {
+		last, err := s.repo.LastCreatedAt(ctx, discordID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get last generation: %w", err)
+		}
+		if last != nil {
+			if until := last.Add(s.limits.Cooldown); until.After(s.now()) {
+				report.CooldownUntil = until
+			}
+		}
+	}
+	return report, nil
+}
+
+// periodStarts は設定されたタイムゾーンでの今日と今月の開始日時を返す
+func (s *Service) periodStarts() (day, month time.Time) {
+	now := s.now().In(s.limits.Location)
+	day = time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, s.limits.Location)
+	month = time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, s.limits.Location)
+	return day, month
+}
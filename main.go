@@ -1,127 +1,36 @@
 package main
 
 import (
-	"context"
 	"fmt"
-	"log"
 	"os"
-	"os/signal"
-	"syscall"
-
-	"github.com/bwmarrin/discordgo"
-	"github.com/joho/godotenv"
-
-	"github.com/chun37/doppelcord/internal/database"
-	"github.com/chun37/doppelcord/internal/handler"
-	"github.com/chun37/doppelcord/internal/llm"
-	"github.com/chun37/doppelcord/internal/repository/cached"
-	"github.com/chun37/doppelcord/internal/repository/postgres"
+	_ "time/tzdata" // コンテナ環境でもタイムゾーンを解決できるようにする
 )
 
-var commands = []*discordgo.ApplicationCommand{
-	{
-		Name:        "register",
-		Description: "ユーザーを登録します",
-	},
-	{
-		Name:        "test",
-		Description: "LLMにテストメッセージを送信します",
-	},
-}
-
 func main() {
-	err := godotenv.Load()
-	if err != nil {
-		log.Fatal("Error loading .env file")
-	}
-
-	token := os.Getenv("DISCORD_BOT_TOKEN")
-	if token == "" {
-		log.Fatal("DISCORD_BOT_TOKEN is not set in .env file")
-	}
-
-	guildID := os.Getenv("GUILD_ID")
-	if guildID == "" {
-		log.Fatal("GUILD_ID is not set in .env file")
-	}
-
-	ctx := context.Background()
-
-	dbConfig := database.Config{
-		Host:     os.Getenv("DB_HOST"),
-		Port:     os.Getenv("DB_PORT"),
-		User:     os.Getenv("DB_USER"),
-		Password: os.Getenv("DB_PASSWORD"),
-		DBName:   os.Getenv("DB_NAME"),
-		SSLMode:  os.Getenv("DB_SSLMODE"),
-	}
-
-	pool, err := database.NewPostgresPool(ctx, dbConfig)
-	if err != nil {
-		log.Fatal("Failed to connect to database:", err)
-	}
-	defer pool.Close()
-
-	fmt.Println("Connected to database")
-
-	pgUserRepo := postgres.NewUserRepository(pool)
-	userRepo := cached.NewCachedUserRepository(pgUserRepo)
-
-	if err := userRepo.LoadAll(ctx); err != nil {
-		log.Fatal("Failed to load users into cache:", err)
-	}
-	fmt.Println("Loaded users into cache")
-
-	msgRepo := postgres.NewMessageRepository(pool)
-
-	// LLM設定の読み込み
-	llmConfig := llm.Config{
-		APIURL: os.Getenv("LLM_API_URL"),
-		APIKey: os.Getenv("LLM_API_KEY"),
-		Model:  os.Getenv("LLM_MODEL"),
-	}
-	if llmConfig.APIURL == "" {
-		log.Fatal("LLM_API_URL is not set in .env file")
-	}
-	if llmConfig.Model == "" {
-		log.Fatal("LLM_MODEL is not set in .env file")
-	}
-	llmClient := llm.NewClient(llmConfig)
-	fmt.Println("LLM client initialized")
-
-	msgHandler := handler.NewMessageHandler(userRepo, msgRepo)
-	interactionHandler := handler.NewInteractionHandler(userRepo, msgRepo, llmClient)
-
-	dg, err := discordgo.New("Bot " + token)
-	if err != nil {
-		log.Fatal("Error creating Discord session:", err)
-	}
-
-	dg.AddHandler(msgHandler.Handle)
-	dg.AddHandler(interactionHandler.Handle)
-
-	dg.Identify.Intents = discordgo.IntentsGuildMessages | discordgo.IntentsDirectMessages | discordgo.IntentsMessageContent
-
-	err = dg.Open()
-	if err != nil {
-		log.Fatal("Error opening connection:", err)
-	}
-	defer dg.Close()
-
-	for _, cmd := range commands {
-		_, err := dg.ApplicationCommandCreate(dg.State.User.ID, guildID, cmd)
-		if err != nil {
-			log.Printf("Cannot create '%s' command: %v", cmd.Name, err)
-		} else {
-			fmt.Printf("Command '%s' registered\n", cmd.Name)
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "eval":
+			runEval(os.Args[2:])
+			return
+		case "mock-llm":
+			runMockLLM(os.Args[2:])
+			return
+		case "-h", "-help", "--help", "help":
+			printUsage()
+			return
 		}
 	}
+	runBot()
+}
 
-	fmt.Println("Bot is now running. Press CTRL-C to exit.")
+func printUsage() {
+	fmt.Fprintf(os.Stderr, `Usage: %[1]s [command]
 
-	sc := make(chan os.Signal, 1)
-	signal.Notify(sc, syscall.SIGINT, syscall.SIGTERM, os.Interrupt)
-	<-sc
+Commands:
+  (none)     Discordボットを起動
+  eval       なりきりの再現度をオフライン評価し、JSONレポートを出力
+  mock-llm   評価用のOpenAI互換モックAPIサーバーを起動
 
-	fmt.Println("\nShutting down gracefully...")
+各コマンドのオプションは "%[1]s <command> -h" で確認できます。
+`, os.Args[0])
 }
@@ -0,0 +1,3 @@
+DROP TRIGGER IF EXISTS users_truncated ON users;
+DROP TRIGGER IF EXISTS users_changed ON users;
+DROP FUNCTION IF EXISTS notify_users_changed();
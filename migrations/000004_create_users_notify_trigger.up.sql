@@ -0,0 +1,27 @@
+-- usersテーブルの変更を他のインスタンスに通知する
+CREATE OR REPLACE FUNCTION notify_users_changed() RETURNS trigger AS $$
+BEGIN
+    IF TG_OP = 'TRUNCATE' THEN
+        PERFORM pg_notify('users_changed', json_build_object('op', TG_OP)::text);
+        RETURN NULL;
+    ELSIF TG_OP = 'DELETE' THEN
+        PERFORM pg_notify('users_changed', json_build_object('op', TG_OP, 'discord_id', OLD.discord_id)::text);
+        RETURN OLD;
+    ELSIF TG_OP = 'UPDATE' THEN
+        PERFORM pg_notify('users_changed', json_build_object('op', TG_OP, 'discord_id', NEW.discord_id, 'old_discord_id', OLD.discord_id)::text);
+        RETURN NEW;
+    END IF;
+    PERFORM pg_notify('users_changed', json_build_object('op', TG_OP, 'discord_id', NEW.discord_id)::text);
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS users_changed ON users;
+CREATE TRIGGER users_changed
+    AFTER INSERT OR UPDATE OR DELETE ON users
+    FOR EACH ROW EXECUTE FUNCTION notify_users_changed();
+
+DROP TRIGGER IF EXISTS users_truncated ON users;
+CREATE TRIGGER users_truncated
+    AFTER TRUNCATE ON users
+    FOR EACH STATEMENT EXECUTE FUNCTION notify_users_changed();
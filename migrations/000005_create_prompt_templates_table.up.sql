@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS prompt_templates (
+    id              BIGSERIAL PRIMARY KEY,
+    scope           VARCHAR(10) NOT NULL CHECK (scope IN ('guild', 'user')),
+    scope_id        VARCHAR(20) NOT NULL,
+    version         INTEGER NOT NULL,
+    system_template TEXT NOT NULL,
+    user_template   TEXT NOT NULL,
+    created_by      VARCHAR(20) NOT NULL,
+    created_at      TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    UNIQUE (scope, scope_id, version)
+);
@@ -0,0 +1,16 @@
+CREATE TABLE IF NOT EXISTS generations (
+    id                BIGSERIAL PRIMARY KEY,
+    discord_id        VARCHAR(20) NOT NULL,
+    guild_id          VARCHAR(20) NOT NULL DEFAULT '',
+    channel_id        VARCHAR(20) NOT NULL,
+    command           VARCHAR(32) NOT NULL,
+    model             VARCHAR(100) NOT NULL,
+    status            VARCHAR(10) NOT NULL CHECK (status IN ('success', 'error')),
+    latency_ms        INTEGER NOT NULL,
+    prompt_tokens     INTEGER NOT NULL DEFAULT 0,
+    completion_tokens INTEGER NOT NULL DEFAULT 0,
+    created_at        TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_generations_discord_id_created_at
+    ON generations (discord_id, created_at DESC);
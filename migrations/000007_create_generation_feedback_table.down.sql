@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS generation_feedback;
+
+ALTER TABLE generations
+    DROP COLUMN IF EXISTS prompt_hash,
+    DROP COLUMN IF EXISTS content;
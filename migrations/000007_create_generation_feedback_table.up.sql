@@ -0,0 +1,12 @@
+ALTER TABLE generations
+    ADD COLUMN IF NOT EXISTS prompt_hash VARCHAR(64) NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS content     TEXT NOT NULL DEFAULT '';
+
+CREATE TABLE IF NOT EXISTS generation_feedback (
+    generation_id BIGINT NOT NULL REFERENCES generations (id) ON DELETE CASCADE,
+    discord_id    VARCHAR(20) NOT NULL,
+    rating        SMALLINT NOT NULL CHECK (rating IN (-1, 1)),
+    created_at    TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    updated_at    TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    PRIMARY KEY (generation_id, discord_id)
+);
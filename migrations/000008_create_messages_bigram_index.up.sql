@@ -0,0 +1,12 @@
+-- 形態素解析なしで日本語を検索するため、本文を文字bigramの配列に分解する。
+-- 空白・記号で区切った各部分から2文字ずつ切り出し、1文字だけの部分はそのまま含める
+CREATE OR REPLACE FUNCTION message_bigrams(content TEXT) RETURNS TEXT[]
+LANGUAGE SQL IMMUTABLE PARALLEL SAFE AS $$
+    SELECT COALESCE(array_agg(DISTINCT substr(segment, i, 2)), '{}')
+    FROM regexp_split_to_table(lower(content), '[[:space:][:punct:]]+') AS segment,
+         generate_series(1, GREATEST(char_length(segment) - 1, 1)) AS i
+    WHERE segment <> ''
+$$;
+
+CREATE INDEX IF NOT EXISTS idx_messages_content_bigrams
+    ON messages USING GIN (message_bigrams(content));
@@ -0,0 +1,5 @@
+CREATE TABLE IF NOT EXISTS persona_consents (
+    discord_id VARCHAR(20) PRIMARY KEY,
+    allowed    BOOLEAN NOT NULL,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP NOT NULL
+);
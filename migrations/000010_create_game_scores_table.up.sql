@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS game_scores (
+    guild_id      VARCHAR(20) NOT NULL,
+    discord_id    VARCHAR(20) NOT NULL,
+    points        INTEGER NOT NULL DEFAULT 0,
+    rounds_played INTEGER NOT NULL DEFAULT 0,
+    updated_at    TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    PRIMARY KEY (guild_id, discord_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_game_scores_guild_id_points
+    ON game_scores (guild_id, points DESC);
@@ -0,0 +1,21 @@
+CREATE TABLE IF NOT EXISTS schedules (
+    id                   BIGSERIAL PRIMARY KEY,
+    discord_id           VARCHAR(20) NOT NULL,
+    guild_id             VARCHAR(20) NOT NULL,
+    channel_id           VARCHAR(20) NOT NULL,
+    kind                 VARCHAR(10) NOT NULL CHECK (kind IN ('cron', 'random')),
+    cron_spec            VARCHAR(100) NOT NULL DEFAULT '',
+    min_interval_minutes INTEGER NOT NULL DEFAULT 0,
+    max_interval_minutes INTEGER NOT NULL DEFAULT 0,
+    topic                TEXT NOT NULL DEFAULT '',
+    ends_at              TIMESTAMP WITH TIME ZONE,
+    next_run_at          TIMESTAMP WITH TIME ZONE NOT NULL,
+    last_run_at          TIMESTAMP WITH TIME ZONE,
+    created_at           TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_schedules_next_run_at
+    ON schedules (next_run_at);
+
+CREATE INDEX IF NOT EXISTS idx_schedules_discord_id
+    ON schedules (discord_id);
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS away_statuses;
@@ -0,0 +1,6 @@
+CREATE TABLE IF NOT EXISTS away_statuses (
+    discord_id VARCHAR(20) PRIMARY KEY,
+    until      TIMESTAMP WITH TIME ZONE NOT NULL,
+    note       VARCHAR(200) NOT NULL DEFAULT '',
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP NOT NULL
+);
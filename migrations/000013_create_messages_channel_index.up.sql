@@ -0,0 +1,2 @@
+CREATE INDEX IF NOT EXISTS idx_messages_channel_id_created_at
+    ON messages (channel_id, created_at DESC);
@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS idx_messages_discord_id_replies;
+
+ALTER TABLE messages
+    DROP COLUMN IF EXISTS thread_parent_id,
+    DROP COLUMN IF EXISTS reply_to_content,
+    DROP COLUMN IF EXISTS reply_to_author_id,
+    DROP COLUMN IF EXISTS reply_to_message_id;
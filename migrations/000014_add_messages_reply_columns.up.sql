@@ -0,0 +1,11 @@
+-- 返信先とスレッドの情報。返信でない・スレッド外の発言は空文字
+ALTER TABLE messages
+    ADD COLUMN IF NOT EXISTS reply_to_message_id VARCHAR(20) NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS reply_to_author_id  VARCHAR(20) NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS reply_to_content    TEXT NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS thread_parent_id    VARCHAR(20) NOT NULL DEFAULT '';
+
+-- 他のユーザーへの返信（会話例）だけを新しい順に取得するための部分インデックス
+CREATE INDEX IF NOT EXISTS idx_messages_discord_id_replies
+    ON messages (discord_id, created_at DESC)
+    WHERE reply_to_content <> '' AND reply_to_author_id <> discord_id;
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"github.com/chun37/doppelcord/internal/config"
+	"github.com/chun37/doppelcord/internal/database"
+	"github.com/chun37/doppelcord/internal/llm"
+	"github.com/chun37/doppelcord/internal/usage"
+)
+
+func newDatabaseConfig(cfg *config.Config) database.Config {
+	return database.Config{
+		Host:            cfg.Database.Host,
+		Port:            cfg.Database.Port,
+		User:            cfg.Database.User,
+		Password:        cfg.Database.Password,
+		DBName:          cfg.Database.Name,
+		SSLMode:         cfg.Database.SSLMode,
+		MaxConns:        cfg.Database.MaxConns,
+		MinConns:        cfg.Database.MinConns,
+		MaxConnLifetime: cfg.Database.MaxConnLifetime,
+		MaxConnIdleTime: cfg.Database.MaxConnIdleTime,
+	}
+}
+
+func newLLMConfig(cfg *config.Config) llm.Config {
+	return llm.Config{
+		APIURL:  cfg.LLM.APIURL,
+		APIKey:  cfg.LLM.APIKey,
+		Model:   cfg.LLM.Model,
+		Timeout: cfg.LLM.Timeout,
+		Defaults: llm.Params{
+			Temperature:    cfg.LLM.Temperature,
+			TopP:           cfg.LLM.TopP,
+			MaxTokens:      cfg.LLM.MaxTokens,
+			Stop:           cfg.LLM.Stop,
+			Seed:           cfg.LLM.Seed,
+			ResponseFormat: cfg.LLM.ResponseFormat,
+		},
+	}
+}
+
+func newUsageLimits(cfg *config.Config) usage.Limits {
+	return usage.Limits{
+		DailyTokens:   cfg.Quota.DailyTokens,
+		MonthlyTokens: cfg.Quota.MonthlyTokens,
+		Cooldown:      cfg.Quota.Cooldown,
+		Location:      cfg.Location(),
+	}
+}